@@ -0,0 +1,83 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/beatlabs/github-auth/internal/api"
+	"github.com/beatlabs/github-auth/jwt"
+)
+
+// App is the GitHub App as returned by the API.
+// See: https://docs.github.com/en/rest/apps/apps#get-the-authenticated-app
+type App struct {
+	ID    int64   `json:"id"`
+	Slug  string  `json:"slug"`
+	Name  string  `json:"name"`
+	Owner Account `json:"owner"`
+
+	// Permissions are the permissions granted to the App at registration,
+	// the ceiling of the permissions of its installation tokens.
+	Permissions jwt.Permissions `json:"permissions"`
+}
+
+// Account is a GitHub user or organization account.
+type Account struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+	Type  string `json:"type"`
+}
+
+// VerifyApp confirms the App ID and private key authenticate against GitHub
+// and returns the authenticated App.
+func (c *Config) VerifyApp(ctx context.Context) (*App, error) {
+	var app App
+	if _, err := c.get(ctx, "/app", &app); err != nil {
+		var ae *api.Error
+		if errors.As(err, &ae) && ae.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("failed to verify app, check the app ID and private key: %w", err)
+		}
+		return nil, fmt.Errorf("failed to verify app: %w", err)
+	}
+	return &app, nil
+}
+
+// EnterpriseVersion returns the GitHub Enterprise Server version, e.g. 3.12.0,
+// from the X-GitHub-Enterprise-Version header of the /meta response.
+// It returns an empty version for github.com, which does not set the header.
+func (c *Config) EnterpriseVersion(ctx context.Context) (string, error) {
+	var meta json.RawMessage
+	resp, err := c.get(ctx, "/meta", &meta)
+	if err != nil {
+		return "", fmt.Errorf("failed to get meta: %w", err)
+	}
+	return resp.Header.Get("X-GitHub-Enterprise-Version"), nil
+}
+
+// get sends a JWT authenticated GET request for the provided uri
+// and decodes the JSON response into v.
+func (c *Config) get(ctx context.Context, uri string, v interface{}) (*http.Response, error) {
+	url, err := c.config.Endpoint.Get(uri)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, body, err := api.Do(c.Client(), req)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
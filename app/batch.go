@@ -0,0 +1,68 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"context"
+	"sync"
+
+	"github.com/beatlabs/github-auth/app/inst"
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/errgroup"
+)
+
+// MintResult is the result of minting the token of an installation.
+type MintResult struct {
+	// Config is the Installation config, which caches the minted token.
+	// It is nil if the config could not be created.
+	Config *inst.Config
+
+	// Token is the minted token, or nil on error.
+	Token *oauth2.Token
+
+	// Err is the error of the installation, if any.
+	Err error
+}
+
+// MintTokens mints the tokens of the provided installations, e.g. to pre-warm them at startup,
+// with at most concurrency token requests at a time (unlimited if not positive).
+// The errors are returned per installation and do not stop the other installations.
+// Once the context is done, the remaining installations fail with its error.
+func (c *Config) MintTokens(ctx context.Context, ids []string, concurrency int) map[string]MintResult {
+	var (
+		mu      sync.Mutex
+		results = make(map[string]MintResult, len(ids))
+		g       errgroup.Group
+	)
+	if concurrency > 0 {
+		g.SetLimit(concurrency)
+	}
+	for _, id := range ids {
+		id := id
+		g.Go(func() error {
+			res := c.mint(ctx, id)
+			mu.Lock()
+			results[id] = res
+			mu.Unlock()
+			return nil
+		})
+	}
+	//nolint:errcheck
+	g.Wait() // the errors are returned per installation
+	return results
+}
+
+// mint mints the token of an installation.
+func (c *Config) mint(ctx context.Context, id string) MintResult {
+	if err := ctx.Err(); err != nil {
+		return MintResult{Err: err}
+	}
+	install, err := c.InstallationConfig(id)
+	if err != nil {
+		return MintResult{Err: err}
+	}
+	token, err := install.TokenSource(ctx).Token()
+	return MintResult{Config: install, Token: token, Err: err}
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConfig_MintTokens(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			max := maxInFlight.Load()
+			if n <= max || maxInFlight.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v3/app/installations/"), "/access_tokens")
+		if id == "3" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		fmt.Fprintf(w, `{"token": "token-%s", "expires_at": "2050-01-01T11:12:13Z"}`, id)
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{"1", "2", "3", "4", "5", "6"}
+	results := c.MintTokens(context.Background(), ids, 2)
+
+	if got := maxInFlight.Load(); got > 2 {
+		t.Errorf("concurrent requests = %d; want at most 2", got)
+	}
+	if len(results) != len(ids) {
+		t.Fatalf("results = %d; want %d", len(results), len(ids))
+	}
+	for _, id := range ids {
+		res := results[id]
+		if id == "3" {
+			if res.Err == nil {
+				t.Errorf("installation 3: expected an error")
+			}
+			continue
+		}
+		if res.Err != nil {
+			t.Errorf("installation %s: %v", id, res.Err)
+			continue
+		}
+		if got, want := res.Token.AccessToken, "token-"+id; got != want {
+			t.Errorf("installation %s: token = %q; want %q", id, got, want)
+		}
+		if !res.Config.HasValidToken() {
+			t.Errorf("installation %s: the config does not cache the token", id)
+		}
+	}
+}
+
+func TestConfig_MintTokens_Cancel(t *testing.T) {
+	c, err := NewConfig("1", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for id, res := range c.MintTokens(ctx, []string{"1", "2"}, 1) {
+		if !errors.Is(res.Err, context.Canceled) {
+			t.Errorf("installation %s: error = %v; want %v", id, res.Err, context.Canceled)
+		}
+	}
+}
@@ -7,31 +7,172 @@
 package app
 
 import (
+	"context"
 	"crypto/rsa"
+	"errors"
+	"io"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/beatlabs/github-auth/app/inst"
+	"github.com/beatlabs/github-auth/endpoint"
 	"github.com/beatlabs/github-auth/jwt"
 )
 
 // Config defines the base GitHub App Config structure.
 type Config struct {
-	jwt jwt.JWT
+	config jwt.Config
+	opts   []jwt.Option
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func new(endpoint *endpoint.Endpoint, id string, key *rsa.PrivateKey, opts []jwt.Option) (*Config, error) {
+	// the key is shared with the derived Installation configs, see SetPrivateKey
+	shared := &atomic.Pointer[rsa.PrivateKey]{}
+	shared.Store(key)
+	c := &Config{
+		config: jwt.Config{
+			JWT:      jwt.JWT{AppID: id, SharedKey: shared, Expires: time.Minute * 10, IssuedAtSkew: jwt.DefaultIssuedAtSkew},
+			Endpoint: endpoint,
+		},
+		opts: opts,
+	}
+	for _, opt := range opts {
+		opt(&c.config)
+	}
+	return c, nil
 }
 
 // NewConfig returns a new GitHub App instance.
-func NewConfig(id string, key *rsa.PrivateKey) (*Config, error) {
-	return &Config{jwt: jwt.JWT{AppID: id, PrivateKey: key, Expires: time.Minute * 10}}, nil
+// The provided options are also applied to the derived Installation Configs.
+func NewConfig(id string, key *rsa.PrivateKey, opts ...jwt.Option) (*Config, error) {
+	endpoint, err := endpoint.New()
+	if err != nil {
+		return nil, err
+	}
+
+	return new(endpoint, id, key, opts)
+}
+
+// NewEnterpriseConfig returns a new GitHub Enterprise App instance.
+// The derived Installation Configs use the same GitHub Enterprise endpoint.
+// The /api/v3 path is appended to bare host URLs unless jwt.WithRawPath is provided,
+// see endpoint.NewEnterprise.
+func NewEnterpriseConfig(url, id string, key *rsa.PrivateKey, opts ...jwt.Option) (*Config, error) {
+	endpoint, err := endpoint.NewEnterprise(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(endpoint, id, key, opts)
+}
+
+// NewConfigWithEndpoint returns a new GitHub App instance using the provided endpoint,
+// e.g. a test server, instead of the default GitHub API.
+// The derived Installation Configs use the same endpoint.
+func NewConfigWithEndpoint(e *endpoint.Endpoint, id string, key *rsa.PrivateKey, opts ...jwt.Option) (*Config, error) {
+	if e == nil {
+		return nil, errors.New("endpoint is nil")
+	}
+
+	return new(e, id, key, opts)
 }
 
 // Client returns an HTTP client with an HTTP transport that adds Authorization headers.
-//
+// The JWTs are signed with the current private key, see SetPrivateKey.
 func (c *Config) Client() *http.Client {
-	return c.jwt.Client()
+	return &http.Client{Transport: c.Transport()}
+}
+
+// Transport returns the HTTP transport of Client, adding Authorization headers,
+// e.g. to compose it with retrying, caching or tracing transports in a custom order.
+func (c *Config) Transport() http.RoundTripper {
+	return &transport{conf: c}
+}
+
+// ClientWithContext returns an HTTP client with an HTTP transport that adds Authorization headers,
+// using the transport of the context's HTTP client unless jwt.WithTransport is set.
+// Once the context is done, the requests of the client fail with its error.
+func (c *Config) ClientWithContext(ctx context.Context) *http.Client {
+	return &http.Client{Transport: &transport{conf: c, ctx: ctx}}
+}
+
+// transport signs each request with a copy of the current App JWT configuration,
+// so that the clients keep working after the private key is replaced.
+type transport struct {
+	conf *Config
+	ctx  context.Context
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	j := t.conf.JWT()
+	client := j.Client()
+	if t.ctx != nil {
+		client = j.ClientWithContext(t.ctx)
+	}
+	start := time.Now()
+	resp, err := client.Transport.RoundTrip(r)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	t.conf.config.ObserveRequest(status, time.Since(start))
+	t.conf.config.Log().Debug("app request", "method", r.Method, "path", r.URL.Path, "status", status)
+	return resp, err
+}
+
+// JWT returns a copy of the App JWT configuration, e.g. to sign JWTs for endpoints
+// which are not wrapped by the package with its Payload method.
+// Changing the copy does not change the config, and the copy keeps the current private key
+// when it is replaced with SetPrivateKey.
+func (c *Config) JWT() jwt.JWT {
+	j := c.config.JWT
+	j.PrivateKey, j.SharedKey = j.Key(), nil
+	j.FallbackKeys = append([]*rsa.PrivateKey(nil), j.FallbackKeys...)
+	j.Header = j.Header.Clone()
+	return j
+}
+
+// SetPrivateKey replaces the private key signing the App JWTs, e.g. when operators rotate it,
+// without a restart. The JWTs signed with the previous key are forgotten,
+// and the Installation configs returned by InstallationConfig, which share the key,
+// also switch to the new key and discard their tokens fetched with the previous one.
+// It is safe to call concurrently with the requests.
+// The previous key can be kept as a fallback with jwt.WithFallbackKeys until it is deleted on GitHub.
+func (c *Config) SetPrivateKey(key *rsa.PrivateKey) {
+	c.config.SetKey(key)
+}
+
+// Validate checks the App ID, the private keys and the JWT expiry, without any network call.
+// It can be used to fail fast on startup.
+func (c *Config) Validate() error {
+	j := c.JWT()
+	return j.Validate()
+}
+
+// Close closes the token store shared with the Installation configs, if it implements io.Closer.
+// Closing a closed config has no effect and returns the same error.
+func (c *Config) Close() error {
+	c.closeOnce.Do(func() {
+		if closer, ok := c.config.Store.(io.Closer); ok {
+			c.closeErr = closer.Close()
+		}
+	})
+	return c.closeErr
 }
 
 // InstallationConfig returns the Installation Config for the provided installation ID.
+// It shares the private key of the App config, see SetPrivateKey.
 func (c *Config) InstallationConfig(id string) (*inst.Config, error) {
-	return inst.NewConfig(c.jwt.AppID, id, c.jwt.PrivateKey)
+	return inst.NewConfig(c.config.AppID, id, nil, c.installationOptions()...)
+}
+
+// installationOptions returns the options of the derived Installation Configs.
+func (c *Config) installationOptions() []jwt.Option {
+	endpoint, shared := c.config.Endpoint, c.config.SharedKey
+	return append([]jwt.Option{func(ic *jwt.Config) { ic.Endpoint, ic.SharedKey = endpoint, shared }}, c.opts...)
 }
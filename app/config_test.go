@@ -0,0 +1,463 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/beatlabs/github-auth/endpoint"
+	"github.com/beatlabs/github-auth/jws"
+	"github.com/beatlabs/github-auth/jwt"
+)
+
+func TestConfig_InstallationConfig_Enterprise(t *testing.T) {
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			got = r.URL.Path
+			w.Header().Set("Content-Type", "application/json")
+			//nolint:errcheck
+			w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	install, err := c.InstallationConfig("2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := install.Client(context.Background()).Get(ts.URL + "/api/v3/installation/repositories")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if want := "/api/v3/app/installations/2/access_tokens"; got != want {
+		t.Errorf("token URL path = %q; want %q", got, want)
+	}
+}
+
+func TestConfig_InstallationConfig_RawPath(t *testing.T) {
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", getPrivateKey(t), jwt.WithRawPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	install, err := c.InstallationConfig("2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := install.TokenSource(context.Background()).Token(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/app/installations/2/access_tokens"; got != want {
+		t.Errorf("token URL path = %q; want %q", got, want)
+	}
+}
+
+func getPrivateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestConfig_VerifyApp(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/app" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{
+			"id": 1,
+			"slug": "octoapp",
+			"name": "Octocat App",
+			"owner": {"login": "github", "id": 2, "type": "Organization"},
+			"permissions": {"metadata": "read", "contents": "write"}
+		}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, err := c.VerifyApp(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := app.Slug, "octoapp"; got != want {
+		t.Errorf("slug = %q; want %q", got, want)
+	}
+	if got, want := app.Owner.Login, "github"; got != want {
+		t.Errorf("owner = %q; want %q", got, want)
+	}
+	if got, want := app.Permissions["contents"], "write"; got != want {
+		t.Errorf("contents permission = %q; want %q", got, want)
+	}
+	if !app.Permissions.Grants("contents", "read") || app.Permissions.Grants("metadata", "write") {
+		t.Errorf("permissions = %v; want contents:write and metadata:read", app.Permissions)
+	}
+}
+
+func TestConfig_VerifyApp_Unauthorized(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		//nolint:errcheck
+		w.Write([]byte(`{"message": "A JSON web token could not be decoded"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.VerifyApp(context.Background())
+	if err == nil {
+		t.Fatal("got no error, expected one")
+	}
+	want := "failed to verify app, check the app ID and private key: 401 Unauthorized: A JSON web token could not be decoded"
+	if got := err.Error(); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestConfig_FallbackKeys(t *testing.T) {
+	oldKey, newKey := getPrivateKey(t), getPrivateKey(t)
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		// only the old key is registered on GitHub
+		if err := jws.Verify(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), &oldKey.PublicKey); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			//nolint:errcheck
+			w.Write([]byte(`{"message": "A JWT could not be decoded"}`))
+			return
+		}
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", newKey, jwt.WithFallbackKeys(getPrivateKey(t), oldKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	install, err := c.InstallationConfig("2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := install.TokenSource(context.Background()).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := token.AccessToken, "v1.1f699f1069f60xxx"; got != want {
+		t.Errorf("access token = %q; want %q", got, want)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d; want 3", calls)
+	}
+
+	// no more fallback keys
+	c, err = NewEnterpriseConfig(ts.URL, "1", newKey, jwt.WithFallbackKeys(getPrivateKey(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	install, err = c.InstallationConfig("3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = install.TokenSource(context.Background()).Token()
+	var ae *jwt.AuthError
+	if !errors.As(err, &ae) || ae.StatusCode != http.StatusUnauthorized {
+		t.Errorf("error = %v; want a 401 *jwt.AuthError", err)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c, err := NewConfig("1", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	c, err = NewConfig("1", getPrivateKey(t), jwt.WithFallbackKeys(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Validate(); err == nil {
+		t.Error("expected an error for the nil fallback key")
+	}
+}
+
+// closingStore is a token store counting its Close calls.
+type closingStore struct {
+	*jwt.MemoryStore
+	closes int
+}
+
+func (s *closingStore) Close() error {
+	s.closes++
+	return errors.New("closed")
+}
+
+func TestConfig_Close(t *testing.T) {
+	store := &closingStore{MemoryStore: jwt.NewMemoryStore()}
+	c, err := NewConfig("1", getPrivateKey(t), jwt.WithTokenStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := c.Close(); err == nil || err.Error() != "closed" {
+			t.Errorf("error = %v; want the store error", err)
+		}
+	}
+	if store.closes != 1 {
+		t.Errorf("closes = %d; want 1", store.closes)
+	}
+}
+
+func TestNewConfigWithEndpoint(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	e, err := endpoint.NewEnterprise(ts.URL, endpoint.WithRawPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewConfigWithEndpoint(e, "1", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	install, err := c.InstallationConfig("2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := install.TokenSource(context.Background()).Token(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(paths, ","), "/app/installations/2/access_tokens"; got != want {
+		t.Errorf("paths = %s; want %s", got, want)
+	}
+
+	if _, err := NewConfigWithEndpoint(nil, "1", getPrivateKey(t)); err == nil {
+		t.Error("expected an error for the nil endpoint")
+	}
+}
+
+func TestWithEndpoint(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/app" {
+			//nolint:errcheck
+			w.Write([]byte(`{"id": 1, "slug": "octoapp"}`))
+			return
+		}
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	e, err := endpoint.NewEnterprise(ts.URL, endpoint.WithRawPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewConfig("1", getPrivateKey(t), jwt.WithEndpoint(e))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.VerifyApp(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	install, err := c.InstallationConfig("2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := install.TokenSource(context.Background()).Token(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(paths, ","), "/app,/app/installations/2/access_tokens"; got != want {
+		t.Errorf("paths = %s; want %s", got, want)
+	}
+}
+
+func TestConfig_EnterpriseVersion(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/meta" {
+			t.Errorf("path = %s; want /api/v3/meta", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-GitHub-Enterprise-Version", "3.12.0")
+		//nolint:errcheck
+		w.Write([]byte(`{"verifiable_password_authentication": false, "installed_version": "3.12.0"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, err := c.EnterpriseVersion(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "3.12.0"; version != want {
+		t.Errorf("version = %q; want %q", version, want)
+	}
+}
+
+func TestConfig_JWT(t *testing.T) {
+	c, err := NewConfig("1", getPrivateKey(t), jwt.WithHeader(http.Header{"X-Proxy-Auth": {"secret"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := c.JWT()
+	payload, err := j.Payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := jws.Verify(payload, &c.config.Key().PublicKey); err != nil {
+		t.Errorf("invalid payload: %v", err)
+	}
+
+	j.AppID = "2"
+	j.Header.Set("X-Proxy-Auth", "changed")
+	if c.config.AppID != "1" || c.config.Header.Get("X-Proxy-Auth") != "secret" {
+		t.Error("changing the copy changed the config")
+	}
+}
+
+func TestConfig_SetPrivateKey(t *testing.T) {
+	oldKey, newKey := getPrivateKey(t), getPrivateKey(t)
+	var mu sync.Mutex
+	current, mints := &oldKey.PublicKey, 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := jws.Verify(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), current); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			mints++
+			//nolint:errcheck
+			w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+			return
+		}
+		//nolint:errcheck
+		w.Write([]byte(`{"id": 1, "slug": "octoapp"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := c.Client()
+	install, err := c.InstallationConfig("2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := install.TokenSource(context.Background()).Token(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	current = &newKey.PublicKey
+	mu.Unlock()
+	c.SetPrivateKey(newKey)
+
+	resp, err := client.Get(ts.URL + "/api/v3/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d; want the existing client to sign with the new key", resp.StatusCode)
+	}
+	if install.HasValidToken() {
+		t.Error("the installation token should be discarded")
+	}
+	if _, err := install.TokenSource(context.Background()).Token(); err != nil {
+		t.Fatal(err)
+	}
+	if mints != 2 {
+		t.Errorf("mints = %d; want 2", mints)
+	}
+	if !c.JWT().PrivateKey.Equal(newKey) {
+		t.Error("the App JWT should use the new key")
+	}
+}
+
+func TestConfig_Transport(t *testing.T) {
+	key := getPrivateKey(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := jws.Verify(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), &key.PublicKey); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"id": 1, "slug": "octoapp"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: c.Transport()}
+	resp, err := client.Get(ts.URL + "/api/v3/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d; want %d", resp.StatusCode, http.StatusOK)
+	}
+}
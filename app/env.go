@@ -0,0 +1,43 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/beatlabs/github-auth/endpoint"
+	"github.com/beatlabs/github-auth/jwt"
+	"github.com/beatlabs/github-auth/key"
+)
+
+var (
+	// EnvAppID is the environment variable holding the App ID.
+	EnvAppID = "APP_ID"
+
+	// EnvPrivateKey is the environment variable holding the App private key,
+	// either PEM encoded or base64 encoded.
+	EnvPrivateKey = "PRIVATE_KEY"
+)
+
+// NewConfigFromEnv returns a new GitHub App instance configured from the environment,
+// e.g. in GitHub Actions: the App ID from APP_ID, the private key from PRIVATE_KEY,
+// PEM or base64 encoded, and the API URL from GITHUB_API_URL if set, see endpoint.NewFromEnv.
+func NewConfigFromEnv(opts ...jwt.Option) (*Config, error) {
+	id := os.Getenv(EnvAppID)
+	if id == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", EnvAppID)
+	}
+	pk, err := key.FromEnv(EnvPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	e, err := endpoint.NewFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("environment variable %s: %w", endpoint.EnvAPIURL, err)
+	}
+
+	return new(e, id, pk, opts)
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+)
+
+func TestNewConfigFromEnv(t *testing.T) {
+	pk := getPrivateKey(t)
+	pemKey := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(pk)}))
+
+	tests := map[string]struct {
+		env     map[string]string
+		wantURL string
+		wantErr string
+	}{
+		"pem": {
+			env:     map[string]string{"APP_ID": "1", "PRIVATE_KEY": pemKey},
+			wantURL: "https://api.github.com",
+		},
+		"base64": {
+			env:     map[string]string{"APP_ID": "1", "PRIVATE_KEY": base64.StdEncoding.EncodeToString([]byte(pemKey)), "GITHUB_API_URL": "https://github.example.com/api/v3"},
+			wantURL: "https://github.example.com/api/v3",
+		},
+		"missing app ID": {
+			env:     map[string]string{"PRIVATE_KEY": pemKey},
+			wantErr: "environment variable APP_ID is not set",
+		},
+		"missing key": {
+			env:     map[string]string{"APP_ID": "1"},
+			wantErr: "environment variable PRIVATE_KEY is not set",
+		},
+		"invalid key": {
+			env:     map[string]string{"APP_ID": "1", "PRIVATE_KEY": "not a key"},
+			wantErr: "environment variable PRIVATE_KEY is neither PEM nor base64 encoded",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			for _, k := range []string{"APP_ID", "PRIVATE_KEY", "GITHUB_API_URL"} {
+				t.Setenv(k, tt.env[k])
+			}
+			c, err := NewConfigFromEnv()
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v; want prefix %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.config.AppID; got != "1" {
+				t.Errorf("app ID = %q; want 1", got)
+			}
+			if !c.config.Key().Equal(pk) {
+				t.Error("the private key does not match")
+			}
+			if got := c.config.Endpoint.String(); got != tt.wantURL {
+				t.Errorf("URL = %s; want %s", got, tt.wantURL)
+			}
+		})
+	}
+}
@@ -10,101 +10,236 @@ package inst
 import (
 	"context"
 	"crypto/rsa"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/beatlabs/github-auth/endpoint"
 	"github.com/beatlabs/github-auth/jwt"
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/singleflight"
 )
 
 // Config defines an GitHub app installation config.
 type Config struct {
 	config jwt.Config
+	id     string
+
+	mu sync.Mutex
+	// cached is only set with mu held, but it can be read without it, see cachedToken.
+	cached atomic.Pointer[oauth2.Token]
+	// cachedKey is the private key the cached token was fetched with, set along with cached.
+	cachedKey atomic.Pointer[rsa.PrivateKey]
+	closed bool
+	// gen is incremented with mu held when the cached token is invalidated, e.g. by a configuration change,
+	// so that the fetches of the previous generation are neither shared nor cached.
+	gen uint64
+	// rejected is the last token rejected by GitHub, replaced by refresh.
+	rejected *oauth2.Token
+	// fetches shares the token fetch of a generation across the concurrent callers.
+	fetches singleflight.Group
 }
 
-func new(endpoint endpoint.Endpoint, appID, instID string, key *rsa.PrivateKey) (*Config, error) {
-	url, err := endpoint.Get(fmt.Sprintf("/app/installations/%s/access_tokens", instID))
+// ErrClosed is returned when a token is requested from a closed Installation config.
+var ErrClosed = errors.New("inst: config is closed")
+
+// Close drops the cached token and makes the following token requests fail with ErrClosed.
+// The token store is not closed, as it can be shared with other configs.
+// Closing a closed config has no effect.
+func (c *Config) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	c.gen++
+	c.cached.Store(nil)
+	return nil
+}
+
+func new(endpoint *endpoint.Endpoint, appID, instID string, key *rsa.PrivateKey, opts []jwt.Option) (*Config, error) {
+	c := &Config{
+		config: jwt.Config{
+			JWT:      jwt.JWT{AppID: appID, PrivateKey: key, Expires: time.Minute * 10, IssuedAtSkew: jwt.DefaultIssuedAtSkew},
+			Endpoint:       endpoint,
+			InstallationID: instID,
+		},
+		id: instID,
+	}
+	for _, opt := range opts {
+		opt(&c.config)
+	}
+	url, err := c.config.Endpoint.Get(fmt.Sprintf("/app/installations/%s/access_tokens", instID))
 	if err != nil {
 		return nil, err
 	}
-	return &Config{
-		config: jwt.Config{
-			JWT:      jwt.JWT{AppID: appID, PrivateKey: key, Expires: time.Minute * 10},
-			TokenURL: url,
-		}}, nil
+	c.config.TokenURL = url
+	return c, nil
 }
 
 // NewConfig returns a new GitHub App instance.
-func NewConfig(appID, instID string, key *rsa.PrivateKey) (*Config, error) {
+func NewConfig(appID, instID string, key *rsa.PrivateKey, opts ...jwt.Option) (*Config, error) {
 	endpoint, err := endpoint.New()
 	if err != nil {
 		return nil, err
 	}
 
-	return new(*endpoint, appID, instID, key)
+	return new(endpoint, appID, instID, key, opts)
 }
 
 // NewEnterpriseConfig returns a new GitHub App instance.
-func NewEnterpriseConfig(url, appID, instID string, key *rsa.PrivateKey) (*Config, error) {
+// The /api/v3 path is appended to bare host URLs unless jwt.WithRawPath is provided,
+// see endpoint.NewEnterprise.
+func NewEnterpriseConfig(url, appID, instID string, key *rsa.PrivateKey, opts ...jwt.Option) (*Config, error) {
 	endpoint, err := endpoint.NewEnterprise(url)
 	if err != nil {
 		return nil, err
 	}
 
-	return new(*endpoint, appID, instID, key)
+	return new(endpoint, appID, instID, key, opts)
+}
+
+// NewConfigWithEndpoint returns a new GitHub App instance using the provided endpoint,
+// e.g. a test server, instead of the default GitHub API.
+func NewConfigWithEndpoint(e *endpoint.Endpoint, appID, instID string, key *rsa.PrivateKey, opts ...jwt.Option) (*Config, error) {
+	if e == nil {
+		return nil, errors.New("endpoint is nil")
+	}
+
+	return new(e, appID, instID, key, opts)
+}
+
+// Endpoint returns the GitHub API endpoint of the installation.
+func (c *Config) Endpoint() *endpoint.Endpoint {
+	return c.config.Endpoint
+}
+
+// InstallationID returns the ID of the installation.
+func (c *Config) InstallationID() string {
+	return c.id
+}
+
+// Validate checks the App ID, the private keys, the JWT expiry, the token URL
+// and the repository IDs, without any network call.
+// It can be used to fail fast on startup.
+func (c *Config) Validate() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.config.Validate()
 }
 
 // SetRepositories returns an updated installation with the provided repositories.
-// Access will be limited to the list of provided repositories
+// Access will be limited to the list of provided repositories.
+// Prefer WithRepositories, which sets them at construction.
 func (c *Config) SetRepositories(names []string) {
-	c.config.Repositories.Names = names
+	c.update(func() { c.config.Repositories.Names = names })
 }
 
 // SetRepositoryIDs returns an updated installation with the provided repository ids.
 // Access will be limited to the list of provided repository IDs.
-//
+// Prefer WithRepositoryIDs, which sets them at construction.
+// The IDs must be numeric, otherwise fetching tokens fails.
 func (c *Config) SetRepositoryIDs(ids []string) {
-	c.config.Repositories.IDs = ids
+	c.update(func() { c.config.Repositories.IDs = ids })
+}
+
+// SetRepositoryIDsInt updates the installation with the provided numeric repository IDs.
+// Access will be limited to the list of provided repository IDs.
+func (c *Config) SetRepositoryIDsInt(ids []int64) {
+	c.SetRepositoryIDs(formatIDs(ids))
+}
+
+// formatIDs formats the numeric repository IDs.
+func formatIDs(ids []int64) []string {
+	if ids == nil {
+		return nil
+	}
+	ss := make([]string, len(ids))
+	for i, id := range ids {
+		ss[i] = strconv.FormatInt(id, 10)
+	}
+	return ss
+}
+
+// SetPermissions updates the installation with the provided permissions.
+// The token access will be limited to the provided subset of the App's permissions,
+// e.g. {"contents": "read", "issues": "write"}.
+func (c *Config) SetPermissions(permissions map[string]string) {
+	c.update(func() { c.config.Permissions = permissions })
+}
+
+// SetPermissionRequest updates the installation with the provided permissions,
+// including the access level of the single_file permission.
+func (c *Config) SetPermissionRequest(p jwt.PermissionRequest) {
+	c.update(func() { c.config.SetPermissionRequest(p) })
+}
+
+// SetPrivateKey replaces the private key signing the JWTs, e.g. when it is rotated,
+// and discards the cached token. It is safe to call concurrently with the token requests.
+// A config returned by app.Config.InstallationConfig then no longer shares the key of the App config.
+func (c *Config) SetPrivateKey(key *rsa.PrivateKey) {
+	c.update(func() { c.config.PrivateKey, c.config.SharedKey = key, nil })
 }
 
 // Client returns an HTTP client wrapping the context's
 // HTTP transport and adding Authorization headers with tokens
 // obtained using JWT.
 //
+// The provided context is only used for its HTTP client, the tokens are
+// refreshed using the context of each request. The client can thus be reused
+// across requests, and a cancelled request stops waiting for its token refresh.
+// The refresh itself goes on for the other requests, for at most a minute.
+//
 // The returned client and its Transport should not be modified.
 func (c *Config) Client(ctx context.Context) *http.Client {
-	return c.config.Client(ctx)
+	return &http.Client{
+		Transport: c.Transport(ctx),
+	}
+}
+
+// Transport returns the HTTP transport of Client, wrapping the context's HTTP transport
+// and adding Authorization headers, e.g. to compose it with retrying, caching or tracing
+// transports in a custom order.
+func (c *Config) Transport(ctx context.Context) http.RoundTripper {
+	return newTransport(ctx, c)
+}
+
+// Expiry returns the expiry of the installation token, fetching one if there is no valid cached token.
+// The token is refreshed before its expiry, see jwt.WithEarlyRefresh.
+func (c *Config) Expiry(ctx context.Context) (time.Time, error) {
+	token, err := c.token(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get token: %w", err)
+	}
+	return token.Expiry, nil
 }
 
 // Permissions returns a map of the GitHub app client's permissions.
-//
 func (c *Config) Permissions() (map[string]string, error) {
-	token, err := c.config.TokenSource(context.Background()).Token()
+	token, err := c.token(context.Background())
 	if err != nil {
-		return nil, fmt.Errorf("failed to get token: %v", err)
+		return nil, fmt.Errorf("failed to get token: %w", err)
 	}
 
-	extra := token.Extra("permissions")
-	pp, ok := extra.(map[string]string)
-	if !ok {
-		return nil, fmt.Errorf("failed to get permissions from extra field: %v", extra)
+	pp, err := jwt.ParsedPermissions(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions from extra field: %w", err)
 	}
 	return pp, nil
 }
 
 // RepositorySelection returns the GitHub app client's repository selection (all or selected).
-//
 func (c *Config) RepositorySelection() (string, error) {
-	token, err := c.config.TokenSource(context.Background()).Token()
+	token, err := c.token(context.Background())
 	if err != nil {
-		return "", fmt.Errorf("failed to get token: %v", err)
+		return "", fmt.Errorf("failed to get token: %w", err)
 	}
 
-	extra := token.Extra("repository_selection")
-	rs, ok := extra.(string)
-	if !ok {
-		return "", fmt.Errorf("failed to get permissions from extra field: %v", extra)
+	rs, err := jwt.ParsedRepositorySelection(token)
+	if err != nil {
+		return "", fmt.Errorf("failed to get repository selection from extra field: %w", err)
 	}
 	return rs, nil
 }
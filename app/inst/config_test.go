@@ -0,0 +1,320 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inst
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/beatlabs/github-auth/endpoint"
+	"github.com/beatlabs/github-auth/jwt"
+)
+
+func TestConfig_SetRepositoryIDs(t *testing.T) {
+	var got struct {
+		IDs []int64 `json:"repository_ids"`
+	}
+	var raw string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("content type = %q; want %q", ct, "application/json")
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		raw = string(body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetRepositoryIDs([]string{"123", "456"})
+
+	_, err = c.TokenSource(context.Background()).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{123, 456}; !reflect.DeepEqual(got.IDs, want) {
+		t.Errorf("repository_ids = %v; want %v", got.IDs, want)
+	}
+	// GitHub expects numbers, not strings
+	if want := `{"repository_ids":[123,456]}`; raw != want {
+		t.Errorf("body = %s; want %s", raw, want)
+	}
+
+	c.SetRepositoryIDsInt([]int64{789})
+	_, err = c.TokenSource(context.Background()).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"repository_ids":[789]}`; raw != want {
+		t.Errorf("body = %s; want %s", raw, want)
+	}
+
+	c.SetRepositoryIDs([]string{"octo/repo"})
+	_, err = c.TokenSource(context.Background()).Token()
+	if err == nil || err.Error() != `jwt: invalid repository ID "octo/repo"` {
+		t.Errorf("error = %v; want an invalid repository ID error", err)
+	}
+}
+
+func TestConfig_SetPermissions(t *testing.T) {
+	var got struct {
+		Permissions map[string]string `json:"permissions"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"contents": "read", "issues": "write"}
+	c.SetPermissions(want)
+
+	_, err = c.TokenSource(context.Background()).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Permissions, want) {
+		t.Errorf("permissions = %v; want %v", got.Permissions, want)
+	}
+}
+
+func TestConfig_SetPermissionRequest(t *testing.T) {
+	var raw string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		raw = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetPermissionRequest(jwt.PermissionRequest{
+		Permissions: map[string]string{"contents": "read"},
+		SingleFile:  "write",
+	})
+	if _, err := c.TokenSource(context.Background()).Token(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"permissions":{"contents":"read","single_file":"write"}}`; raw != want {
+		t.Errorf("body = %s; want %s", raw, want)
+	}
+}
+
+func getPrivateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestConfig_Permissions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z", "permissions": {"contents": "read"}, "repository_selection": "all"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := c.Permissions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pp) != 1 || pp["contents"] != "read" {
+		t.Errorf("permissions = %v; want contents:read", pp)
+	}
+	rs, err := c.RepositorySelection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rs, "all"; got != want {
+		t.Errorf("repository selection = %q; want %q", got, want)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c, err := NewConfig("1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	c.SetRepositoryIDs([]string{"octo/repo"})
+	if err := c.Validate(); err == nil || err.Error() != `jwt: invalid repository ID "octo/repo"` {
+		t.Errorf("error = %v; want an invalid repository ID error", err)
+	}
+}
+
+func TestWithRepositories(t *testing.T) {
+	var raw string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		raw = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t), WithRepositories("repo"), WithRepositoryIDs(123, 456))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.TokenSource(context.Background()).Token(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"repositories":["repo"],"repository_ids":[123,456]}`; raw != want {
+		t.Errorf("body = %s; want %s", raw, want)
+	}
+}
+
+func TestConfig_InstallationID(t *testing.T) {
+	c, err := NewConfig("1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.InstallationID(), "2"; got != want {
+		t.Errorf("installation ID = %q; want %q", got, want)
+	}
+}
+
+func TestNewConfig_IssuedAtSkew(t *testing.T) {
+	c, err := NewConfig("1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.config.IssuedAtSkew, jwt.DefaultIssuedAtSkew; got != want {
+		t.Errorf("issued at skew = %s; want %s", got, want)
+	}
+	c, err = NewConfig("1", "2", getPrivateKey(t), jwt.WithIssuedAtSkew(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.config.IssuedAtSkew; got != 0 {
+		t.Errorf("issued at skew = %s; want 0", got)
+	}
+}
+
+func TestNewConfigWithEndpoint(t *testing.T) {
+	var path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	e, err := endpoint.NewEnterprise(ts.URL, endpoint.WithRawPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewConfigWithEndpoint(e, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.TokenSource(context.Background()).Token(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/app/installations/2/access_tokens"; path != want {
+		t.Errorf("path = %s; want %s", path, want)
+	}
+
+	if _, err := NewConfigWithEndpoint(nil, "1", "2", getPrivateKey(t)); err == nil {
+		t.Error("expected an error for the nil endpoint")
+	}
+}
+
+func TestNewEnterpriseConfig_RawPath(t *testing.T) {
+	var path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t), jwt.WithRawPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.TokenSource(context.Background()).Token(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/app/installations/2/access_tokens"; path != want {
+		t.Errorf("path = %s; want %s", path, want)
+	}
+}
+
+func TestWithEndpoint(t *testing.T) {
+	var path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	e, err := endpoint.NewEnterprise(ts.URL, endpoint.WithRawPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewConfig("1", "2", getPrivateKey(t), jwt.WithEndpoint(e))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.TokenSource(context.Background()).Token(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/app/installations/2/access_tokens"; path != want {
+		t.Errorf("path = %s; want %s", path, want)
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inst
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// gitUsername is the username of the HTTPS git operations authenticated with installation tokens.
+const gitUsername = "x-access-token"
+
+// WriteGitCredentials writes the installation token in the git credential helper format,
+// to answer the get action of a helper set with git config credential.helper.
+// It keeps the short-lived token out of the remote URLs.
+// See: https://git-scm.com/docs/git-credential#IOFMT
+func (c *Config) WriteGitCredentials(ctx context.Context, w io.Writer) error {
+	token, err := c.token(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get token: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "username=%s\npassword=%s\n", gitUsername, token.AccessToken)
+	return err
+}
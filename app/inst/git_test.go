@@ -0,0 +1,34 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inst
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConfig_WriteGitCredentials(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := c.WriteGitCredentials(context.Background(), &b); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "username=x-access-token\npassword=v1.1f699f1069f60xxx\n"; got != want {
+		t.Errorf("credentials = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inst
+
+import "github.com/beatlabs/github-auth/jwt"
+
+// WithRepositories limits the token access to the provided repository names.
+// Unlike SetRepositories, the access is set at construction,
+// so that the config does not change while it is used.
+func WithRepositories(names ...string) jwt.Option {
+	return func(c *jwt.Config) {
+		c.Repositories.Names = names
+	}
+}
+
+// WithRepositoryIDs limits the token access to the provided repository IDs.
+// Unlike SetRepositoryIDs, the access is set at construction,
+// so that the config does not change while it is used.
+func WithRepositoryIDs(ids ...int64) jwt.Option {
+	return func(c *jwt.Config) {
+		c.Repositories.IDs = formatIDs(ids)
+	}
+}
+
+// WithScope sets the default scope of the installation tokens, e.g. the least privileges of a tool,
+// applied to every token fetch unless overridden with ScopedToken or TokenForRepos.
+// It replaces the repositories and permissions set by the previous options.
+//
+// The scope can only reduce the access granted to the installation, within the permissions
+// of the App (see app.App.Permissions): GitHub rejects the token requests for more with a 422 response.
+func WithScope(scope ScopeRequest) jwt.Option {
+	return scope.apply
+}
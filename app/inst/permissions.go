@@ -0,0 +1,38 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inst
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/beatlabs/github-auth/jwt"
+)
+
+// ErrInsufficientPermissions is returned when the installation token lacks required permissions.
+var ErrInsufficientPermissions = errors.New("inst: insufficient permissions")
+
+// CheckPermissions verifies that the installation token grants the required permissions,
+// e.g. {"contents": "write"}, fetching a token if there is no valid cached token.
+// The access levels are compared as with jwt.Permissions.Grants.
+// The returned error wraps ErrInsufficientPermissions and lists every missing or insufficient permission.
+func (c *Config) CheckPermissions(ctx context.Context, required map[string]string) error {
+	token, err := c.token(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get token: %w", err)
+	}
+	granted, err := jwt.ParsedPermissions(token)
+	if err != nil {
+		return fmt.Errorf("failed to get permissions from extra field: %w", err)
+	}
+
+	missing := jwt.Permissions(granted).Missing(required)
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrInsufficientPermissions, strings.Join(missing, "; "))
+}
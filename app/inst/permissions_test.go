@@ -0,0 +1,54 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inst
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConfig_CheckPermissions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z", "permissions": {"contents": "read", "issues": "write", "administration": "admin"}}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		required map[string]string
+		wantErr  string
+	}{
+		"none":           {},
+		"exact":          {required: map[string]string{"contents": "read", "issues": "write"}},
+		"lower level":    {required: map[string]string{"issues": "read", "administration": "write"}},
+		"insufficient":   {required: map[string]string{"contents": "write"}, wantErr: "inst: insufficient permissions: contents: read, want write"},
+		"missing":        {required: map[string]string{"pull_requests": "read"}, wantErr: "inst: insufficient permissions: pull_requests: missing, want read"},
+		"unknown level":  {required: map[string]string{"contents": "maintain"}, wantErr: "inst: insufficient permissions: contents: read, want maintain"},
+		"several issues": {required: map[string]string{"pull_requests": "read", "contents": "admin", "issues": "write"}, wantErr: "inst: insufficient permissions: contents: read, want admin; pull_requests: missing, want read"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := c.CheckPermissions(context.Background(), tt.required)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInsufficientPermissions) || err.Error() != tt.wantErr {
+				t.Errorf("error = %v; want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
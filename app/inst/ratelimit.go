@@ -0,0 +1,72 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inst
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/beatlabs/github-auth/internal/api"
+	"github.com/beatlabs/github-auth/jwt"
+)
+
+// RateLimit is the rate limit status of the installation token.
+// See: https://docs.github.com/en/rest/rate-limit/rate-limit#get-rate-limit-status-for-the-authenticated-user
+type RateLimit struct {
+	// Core is the rate limit of the REST API.
+	Core jwt.RateLimit
+
+	// Search is the rate limit of the search API.
+	Search jwt.RateLimit
+
+	// GraphQL is the rate limit of the GraphQL API.
+	GraphQL jwt.RateLimit
+}
+
+// rateLimitResource is a rate limit bucket as returned by the API.
+type rateLimitResource struct {
+	Limit     int   `json:"limit"`
+	Remaining int   `json:"remaining"`
+	Reset     int64 `json:"reset"`
+}
+
+func (r rateLimitResource) rateLimit() jwt.RateLimit {
+	return jwt.RateLimit{Limit: r.Limit, Remaining: r.Remaining, Reset: time.Unix(r.Reset, 0)}
+}
+
+// RateLimit returns the rate limit status of the installation token.
+// Requesting it does not count against the rate limit.
+func (c *Config) RateLimit(ctx context.Context) (*RateLimit, error) {
+	url, err := c.config.Endpoint.Get("/rate_limit")
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	_, body, err := api.Do(c.Client(ctx), req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rate limit: %w", err)
+	}
+	var res struct {
+		Resources struct {
+			Core    rateLimitResource `json:"core"`
+			Search  rateLimitResource `json:"search"`
+			GraphQL rateLimitResource `json:"graphql"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, fmt.Errorf("failed to get rate limit: %w", err)
+	}
+	return &RateLimit{
+		Core:    res.Resources.Core.rateLimit(),
+		Search:  res.Resources.Search.rateLimit(),
+		GraphQL: res.Resources.GraphQL.rateLimit(),
+	}, nil
+}
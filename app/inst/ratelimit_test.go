@@ -0,0 +1,56 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inst
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/beatlabs/github-auth/jwt"
+)
+
+func TestConfig_RateLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v3/app/installations/2/access_tokens":
+			//nolint:errcheck
+			w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+		case "/api/v3/rate_limit":
+			if got, want := r.Header.Get("Authorization"), "token v1.1f699f1069f60xxx"; got != want {
+				t.Errorf("authorization = %q; want %q", got, want)
+			}
+			//nolint:errcheck
+			w.Write([]byte(`{"resources": {
+				"core": {"limit": 5000, "used": 1, "remaining": 4999, "reset": 1691591363},
+				"search": {"limit": 30, "used": 12, "remaining": 18, "reset": 1691591091},
+				"graphql": {"limit": 5000, "used": 7, "remaining": 4993, "reset": 1691593228}
+			}}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl, err := c.RateLimit(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RateLimit{
+		Core:    jwt.RateLimit{Limit: 5000, Remaining: 4999, Reset: time.Unix(1691591363, 0)},
+		Search:  jwt.RateLimit{Limit: 30, Remaining: 18, Reset: time.Unix(1691591091, 0)},
+		GraphQL: jwt.RateLimit{Limit: 5000, Remaining: 4993, Reset: time.Unix(1691593228, 0)},
+	}
+	if *rl != want {
+		t.Errorf("rate limit = %+v; want %+v", *rl, want)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inst
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/beatlabs/github-auth/internal/api"
+)
+
+// Repository is a repository accessible to the installation.
+// See: https://docs.github.com/en/rest/apps/installations#list-repositories-accessible-to-the-app-installation
+type Repository struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	FullName string `json:"full_name"`
+	Private  bool   `json:"private"`
+}
+
+// repositoriesPerPage is the maximum page size of the repositories list.
+const repositoriesPerPage = 100
+
+// Repositories returns all the repositories accessible to the installation,
+// which are limited by SetRepositories and SetRepositoryIDs if set.
+func (c *Config) Repositories(ctx context.Context) ([]Repository, error) {
+	url, err := c.config.Endpoint.Get(fmt.Sprintf("/installation/repositories?per_page=%d", repositoriesPerPage))
+	if err != nil {
+		return nil, err
+	}
+	var repos []Repository
+	err = api.Pages(ctx, c.Client(ctx), url, func(body []byte) error {
+		var page struct {
+			Repositories []Repository `json:"repositories"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return err
+		}
+		repos = append(repos, page.Repositories...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+	return repos, nil
+}
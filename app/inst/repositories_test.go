@@ -0,0 +1,92 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inst
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConfig_Repositories(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v3/app/installations/2/access_tokens":
+			//nolint:errcheck
+			w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+		case "/api/v3/installation/repositories":
+			if got, want := r.Header.Get("Authorization"), "token v1.1f699f1069f60xxx"; got != want {
+				t.Errorf("authorization = %q; want %q", got, want)
+			}
+			if got, want := r.URL.Query().Get("per_page"), "100"; got != want {
+				t.Errorf("per_page = %q; want %q", got, want)
+			}
+			page := r.URL.Query().Get("page")
+			switch page {
+			case "":
+				w.Header().Set("Link", `<`+ts.URL+`/api/v3/installation/repositories?per_page=100&page=2>; rel="next", <`+ts.URL+`/api/v3/installation/repositories?per_page=100&page=2>; rel="last"`)
+				//nolint:errcheck
+				w.Write([]byte(`{"total_count": 2, "repositories": [{"id": 1, "name": "repo1", "full_name": "octo/repo1", "private": false}]}`))
+			case "2":
+				//nolint:errcheck
+				w.Write([]byte(`{"total_count": 2, "repositories": [{"id": 2, "name": "repo2", "full_name": "octo/repo2", "private": true}]}`))
+			default:
+				t.Errorf("unexpected page %q", page)
+			}
+		default:
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos, err := c.Repositories(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Repository{
+		{ID: 1, Name: "repo1", FullName: "octo/repo1"},
+		{ID: 2, Name: "repo2", FullName: "octo/repo2", Private: true},
+	}
+	if len(repos) != len(want) {
+		t.Fatalf("repositories = %+v; want %+v", repos, want)
+	}
+	for i := range want {
+		if repos[i] != want[i] {
+			t.Errorf("repositories[%d] = %+v; want %+v", i, repos[i], want[i])
+		}
+	}
+}
+
+func TestConfig_Repositories_Error(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/v3/app/installations/2/access_tokens" {
+			//nolint:errcheck
+			w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+		//nolint:errcheck
+		w.Write([]byte(`{"message": "Resource not accessible by integration"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Repositories(context.Background())
+	if got, want := fmt.Sprint(err), "failed to list repositories: 403 Forbidden: Resource not accessible by integration"; got != want {
+		t.Errorf("error = %q; want %q", got, want)
+	}
+}
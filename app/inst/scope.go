@@ -0,0 +1,71 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inst
+
+import (
+	"context"
+
+	"github.com/beatlabs/github-auth/jwt"
+	"golang.org/x/oauth2"
+)
+
+// ScopeRequest limits the access of a single installation token.
+// See: https://docs.github.com/en/rest/apps/apps#create-an-installation-access-token-for-an-app
+type ScopeRequest struct {
+	// Repositories is the optional list of repository names to limit the token access to.
+	Repositories []string
+
+	// RepositoryIDs is the optional list of repository IDs to limit the token access to.
+	RepositoryIDs []int64
+
+	// Permissions is the optional subset of the App's permissions to limit the token access to,
+	// e.g. {"contents": "read"}.
+	Permissions map[string]string
+}
+
+// ScopedToken fetches a new token limited to the repositories and permissions of the request,
+// e.g. for a single job, overriding the default scope of the configuration. The installation configuration and its cached token are not modified,
+// and the scoped tokens are not cached.
+func (c *Config) ScopedToken(ctx context.Context, scope ScopeRequest) (*oauth2.Token, error) {
+	c.mu.Lock()
+	conf, closed := c.config, c.closed
+	c.mu.Unlock()
+	if closed {
+		return nil, ErrClosed
+	}
+
+	scope.apply(&conf)
+	return conf.Token(ctx)
+}
+
+// apply limits the token access of the configuration to the scope, replacing its previous limits.
+func (s ScopeRequest) apply(c *jwt.Config) {
+	c.Repositories.Names = s.Repositories
+	c.Repositories.IDs = formatIDs(s.RepositoryIDs)
+	c.Permissions = s.Permissions
+}
+
+// SetScope updates the installation with the provided default scope, applied to its token fetches,
+// and discards the cached token. See WithScope.
+func (c *Config) SetScope(scope ScopeRequest) {
+	c.update(func() { scope.apply(&c.config) })
+}
+
+// TokenForRepos fetches a new token limited to the provided repository names, e.g. for a job
+// processing a single repository, keeping the permissions of the installation configuration.
+// Unlike SetRepositories, the configuration and its cached token are not modified,
+// so it is safe to call concurrently with different repositories. The tokens are not cached.
+func (c *Config) TokenForRepos(ctx context.Context, names []string) (*oauth2.Token, error) {
+	c.mu.Lock()
+	conf, closed := c.config, c.closed
+	c.mu.Unlock()
+	if closed {
+		return nil, ErrClosed
+	}
+
+	conf.Repositories.Names = append([]string(nil), names...)
+	conf.Repositories.IDs = nil
+	return conf.Token(ctx)
+}
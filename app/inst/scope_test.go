@@ -0,0 +1,196 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inst
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/beatlabs/github-auth/jwt"
+)
+
+func TestConfig_ScopedToken(t *testing.T) {
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		bodies = append(bodies, string(body))
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetRepositories([]string{"shared"})
+
+	scopes := []ScopeRequest{
+		{Repositories: []string{"repo"}, RepositoryIDs: []int64{123}, Permissions: map[string]string{"contents": "read"}},
+		{Permissions: map[string]string{"issues": "write"}},
+	}
+	for _, scope := range scopes {
+		if _, err := c.ScopedToken(context.Background(), scope); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.TokenSource(context.Background()).Token(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`{"repositories":["repo"],"repository_ids":[123],"permissions":{"contents":"read"}}`,
+		`{"permissions":{"issues":"write"}}`,
+		`{"repositories":["shared"]}`,
+	}
+	if len(bodies) != len(want) {
+		t.Fatalf("requests = %d; want %d", len(bodies), len(want))
+	}
+	for i := range want {
+		if bodies[i] != want[i] {
+			t.Errorf("body %d = %s; want %s", i, bodies[i], want[i])
+		}
+	}
+}
+
+func TestConfig_TokenForRepos(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Repositories []string          `json:"repositories"`
+			Permissions  map[string]string `json:"permissions"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		if len(req.Repositories) != 1 || req.Permissions["contents"] != "read" {
+			t.Errorf("request = %+v; want a single repository and the config permissions", req)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		// the token is named after the repository it is limited to
+		//nolint:errcheck
+		json.NewEncoder(w).Encode(map[string]string{"token": "token-" + req.Repositories[0], "expires_at": "2050-01-01T11:12:13Z"})
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetRepositories([]string{"shared"})
+	c.SetPermissions(map[string]string{"contents": "read"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(repo string) {
+			defer wg.Done()
+			token, err := c.TokenForRepos(context.Background(), []string{repo})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if want := "token-" + repo; token.AccessToken != want {
+				t.Errorf("token = %s; want %s", token.AccessToken, want)
+			}
+		}(fmt.Sprintf("repo-%d", i))
+	}
+	wg.Wait()
+
+	if got := c.config.Repositories.Names; len(got) != 1 || got[0] != "shared" {
+		t.Errorf("config repositories = %v; want [shared]", got)
+	}
+	if c.HasValidToken() {
+		t.Error("the repository tokens should not be cached")
+	}
+}
+
+func TestWithScope(t *testing.T) {
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		bodies = append(bodies, string(body))
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(string(body), "administration") {
+			// the App is not granted the administration permission
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			//nolint:errcheck
+			w.Write([]byte(`{"message": "The permissions requested are not granted to this installation."}`))
+			return
+		}
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z", "permissions": {"contents": "read"}}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t), WithScope(ScopeRequest{
+		Repositories: []string{"repo"},
+		Permissions:  map[string]string{"contents": "read"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.TokenSource(context.Background()).Token(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.TokenForRepos(context.Background(), []string{"other"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ScopedToken(context.Background(), ScopeRequest{Permissions: map[string]string{"issues": "read"}}); err != nil {
+		t.Fatal(err)
+	}
+	c.SetScope(ScopeRequest{Permissions: map[string]string{"administration": "write"}})
+	if _, err := c.TokenSource(context.Background()).Token(); err == nil {
+		t.Error("expected an error for permissions beyond the App's")
+	}
+
+	want := []string{
+		`{"repositories":["repo"],"permissions":{"contents":"read"}}`,
+		`{"repositories":["other"],"permissions":{"contents":"read"}}`,
+		`{"permissions":{"issues":"read"}}`,
+		`{"permissions":{"administration":"write"}}`,
+	}
+	if len(bodies) != len(want) {
+		t.Fatalf("requests = %d; want %d", len(bodies), len(want))
+	}
+	for i := range want {
+		if bodies[i] != want[i] {
+			t.Errorf("body %d = %s; want %s", i, bodies[i], want[i])
+		}
+	}
+}
+
+func TestConfig_TokenForRepos_TooMany(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected token request")
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, jwt.MaxRepositories+1)
+	for i := range names {
+		names[i] = fmt.Sprintf("repo-%d", i)
+	}
+	if _, err := c.TokenForRepos(context.Background(), names); !errors.Is(err, jwt.ErrTooManyRepositories) {
+		t.Errorf("error = %v; want jwt.ErrTooManyRepositories", err)
+	}
+}
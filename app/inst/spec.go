@@ -0,0 +1,59 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inst
+
+import (
+	"crypto/rsa"
+
+	"github.com/beatlabs/github-auth/endpoint"
+	"github.com/beatlabs/github-auth/jwt"
+)
+
+// Spec is the JSON serializable part of an Installation config, without the private key,
+// e.g. for declarative configuration files which do not embed secrets.
+type Spec struct {
+	AppID          string `json:"app_id"`
+	InstallationID string `json:"installation_id"`
+
+	// URL is the GitHub API URL, e.g. https://github.example.com/api/v3.
+	// If empty, the default GitHub API URL is used.
+	URL string `json:"url,omitempty"`
+
+	Repositories  []string          `json:"repositories,omitempty"`
+	RepositoryIDs []string          `json:"repository_ids,omitempty"`
+	Permissions   map[string]string `json:"permissions,omitempty"`
+}
+
+// Spec returns the JSON serializable part of the config, without the private key.
+func (c *Config) Spec() Spec {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Spec{
+		AppID:          c.config.AppID,
+		InstallationID: c.id,
+		URL:            c.config.Endpoint.String(),
+		Repositories:   c.config.Repositories.Names,
+		RepositoryIDs:  c.config.Repositories.IDs,
+		Permissions:    c.config.Permissions,
+	}
+}
+
+// NewConfigFromSpec returns a new Installation config from the spec and the provided private key.
+// The options are applied after the spec.
+func NewConfigFromSpec(s Spec, key *rsa.PrivateKey, opts ...jwt.Option) (*Config, error) {
+	e, err := endpoint.New()
+	if s.URL != "" {
+		e, err = endpoint.NewEnterprise(s.URL, endpoint.WithRawPath())
+	}
+	if err != nil {
+		return nil, err
+	}
+	spec := func(c *jwt.Config) {
+		c.Repositories.Names = s.Repositories
+		c.Repositories.IDs = s.RepositoryIDs
+		c.Permissions = s.Permissions
+	}
+	return new(e, s.AppID, s.InstallationID, key, append([]jwt.Option{spec}, opts...))
+}
@@ -0,0 +1,44 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inst
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestConfig_Spec(t *testing.T) {
+	c, err := NewEnterpriseConfig("https://github.example.com", "1", "2", getPrivateKey(t),
+		WithRepositories("repo"), WithRepositoryIDs(123))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetPermissions(map[string]string{"contents": "read"})
+
+	b, err := json.Marshal(c.Spec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"app_id":"1","installation_id":"2","url":"https://github.example.com/api/v3","repositories":["repo"],"repository_ids":["123"],"permissions":{"contents":"read"}}`
+	if string(b) != want {
+		t.Errorf("spec = %s; want %s", b, want)
+	}
+
+	var s Spec
+	if err := json.Unmarshal(b, &s); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := NewConfigFromSpec(s, getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Spec(), c.Spec()) {
+		t.Errorf("loaded spec = %+v; want %+v", loaded.Spec(), c.Spec())
+	}
+	if got, want := loaded.config.TokenURL, c.config.TokenURL; got != want {
+		t.Errorf("token URL = %s; want %s", got, want)
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inst
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/beatlabs/github-auth/jwt"
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/singleflight"
+)
+
+// TokenSource returns a token source which reuses the installation's cached token
+// and refreshes it when expired.
+// The provided context is used to fetch new tokens.
+func (c *Config) TokenSource(ctx context.Context) oauth2.TokenSource {
+	return tokenSource{ctx: ctx, conf: c}
+}
+
+// tokenSource is a source bound to a context that returns the installation's cached token.
+type tokenSource struct {
+	ctx  context.Context
+	conf *Config
+}
+
+func (ts tokenSource) Token() (*oauth2.Token, error) {
+	return ts.conf.token(ts.ctx)
+}
+
+// token returns the cached token, fetching a new one if it is missing or expired.
+// The token store, if any, is consulted before fetching and updated after.
+// The mutex is not held while waiting for the token, so that each caller stops once its context is done.
+func (c *Config) token(ctx context.Context) (*oauth2.Token, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if cached := c.cachedToken(); c.fresh(cached) {
+		c.mu.Unlock()
+		c.config.Log().Debug("token cache hit")
+		c.config.Recorder().ObserveToken(c.id, jwt.ResultCacheHit, 0, 0)
+		return cached, nil
+	}
+	s := c.snapshot()
+	ch := c.share(ctx, s.gen, func(ctx context.Context) (*oauth2.Token, error) {
+		return c.load(ctx, s)
+	})
+	c.mu.Unlock()
+	return wait(ctx, ch)
+}
+
+// load returns the token of the store if it is fresh, or fetches a new one and saves it.
+// Store failures are ignored and a new token is fetched, unless jwt.StoreFailClosed is set.
+func (c *Config) load(ctx context.Context, s snapshot) (*oauth2.Token, error) {
+	log := s.conf.Log()
+	if store := s.conf.Store; store != nil {
+		token, err := store.Get(ctx, s.key)
+		if err != nil {
+			log.Error("failed to get token from store", "error", err)
+			if s.conf.StoreFailureMode == jwt.StoreFailClosed {
+				return nil, fmt.Errorf("%w: %w", jwt.ErrTokenStore, err)
+			}
+		} else if c.fresh(token) {
+			log.Debug("token store hit")
+			s.conf.Recorder().ObserveToken(c.id, jwt.ResultCacheHit, 0, 0)
+			c.keep(s, token)
+			return token, nil
+		}
+	}
+	log.Debug("token cache miss")
+	token, err := s.conf.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.save(ctx, s, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// refresh replaces the stale token, e.g. revoked by GitHub before its expiry, with a new one.
+// The token store is not consulted as it may hold the same token.
+// If the cached token was already replaced by a fresh one, it is returned instead.
+// The concurrent refreshes of the stale token and token requests share the same fetch.
+func (c *Config) refresh(ctx context.Context, stale *oauth2.Token) (*oauth2.Token, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if cached := c.cachedToken(); cached != stale && c.fresh(cached) {
+		c.mu.Unlock()
+		return cached, nil
+	}
+	if c.rejected != stale {
+		c.rejected = stale
+		c.gen++
+		c.cached.Store(nil)
+		c.config.Log().Info("refreshing rejected token")
+	}
+	s := c.snapshot()
+	ch := c.share(ctx, s.gen, func(ctx context.Context) (*oauth2.Token, error) {
+		token, err := s.conf.Token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.save(ctx, s, token); err != nil {
+			return nil, err
+		}
+		return token, nil
+	})
+	c.mu.Unlock()
+	return wait(ctx, ch)
+}
+
+// save sets the token in the token store, if any, and caches it.
+// Store failures are logged as the token is still usable by this config,
+// unless jwt.StoreFailClosed is set: the token is then not cached and the failure is returned.
+func (c *Config) save(ctx context.Context, s snapshot, token *oauth2.Token) error {
+	if store := s.conf.Store; store != nil {
+		if err := store.Set(ctx, s.key, token); err != nil {
+			s.conf.Log().Error("failed to set token in store", "error", err)
+			if s.conf.StoreFailureMode == jwt.StoreFailClosed {
+				return fmt.Errorf("%w: %w", jwt.ErrTokenStore, err)
+			}
+		}
+	}
+	c.keep(s, token)
+	return nil
+}
+
+// keep caches the token, unless the config was changed or closed since its fetch started.
+func (c *Config) keep(s snapshot, token *oauth2.Token) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed && c.gen == s.gen {
+		c.cached.Store(token)
+		c.cachedKey.Store(s.conf.PrivateKey)
+	}
+}
+
+// cachedToken returns the cached token, or nil if it was fetched with another private key
+// than the one shared with the App config, which replaced it.
+func (c *Config) cachedToken() *oauth2.Token {
+	t := c.cached.Load()
+	if shared := c.config.SharedKey; t != nil && shared != nil && shared.Load() != c.cachedKey.Load() {
+		return nil
+	}
+	return t
+}
+
+// snapshot is the configuration of a token fetch, copied with mu held so that the fetch runs without it.
+// The fetch is signed with the current private key, even if the shared key is replaced meanwhile.
+type snapshot struct {
+	conf jwt.Config
+	key  string
+	gen  uint64
+}
+
+func (c *Config) snapshot() snapshot {
+	conf := c.config
+	conf.PrivateKey, conf.SharedKey = conf.Key(), nil
+	return snapshot{conf: conf, key: c.storeKey(), gen: c.gen}
+}
+
+// fetchTimeout bounds the shared token fetches, which are not cancelled with the context of their callers.
+const fetchTimeout = time.Minute
+
+// share starts the token fetch of the generation, or joins the in-flight one, with mu held.
+// The shared fetch is not cancelled with the context of the caller which started it,
+// so that the other callers still get its result.
+func (c *Config) share(ctx context.Context, gen uint64, fetch func(context.Context) (*oauth2.Token, error)) <-chan singleflight.Result {
+	return c.fetches.DoChan(strconv.FormatUint(gen, 10), func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+		defer cancel()
+		return fetch(ctx)
+	})
+}
+
+// wait returns the token of the shared fetch, or the error of the context once it is done.
+func wait(ctx context.Context, ch <-chan singleflight.Result) (*oauth2.Token, error) {
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*oauth2.Token), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// timeNow returns the current time; it is replaced in tests.
+var timeNow = time.Now
+
+// HasValidToken reports whether a non-expired token is cached, without fetching one.
+// It does not wait for an in-flight token fetch.
+func (c *Config) HasValidToken() bool {
+	t := c.cachedToken()
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || c.now().Before(t.Expiry)
+}
+
+// fresh reports whether the token is valid and not within the early refresh buffer of its expiry.
+func (c *Config) fresh(t *oauth2.Token) bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	if t.Expiry.IsZero() {
+		return true
+	}
+	return c.now().Before(t.Expiry.Add(-c.config.RefreshBuffer()))
+}
+
+// now returns the current time of the configured clock, if any.
+func (c *Config) now() time.Time {
+	if c.config.Clock != nil {
+		return c.config.Clock()
+	}
+	return timeNow()
+}
+
+// storeKey returns the token store key: the installation ID,
+// suffixed with a hash of the restrictions if the token access is limited.
+func (c *Config) storeKey() string {
+	if len(c.config.Repositories.Names) == 0 && len(c.config.Repositories.IDs) == 0 &&
+		len(c.config.Permissions) == 0 {
+		return c.id
+	}
+	b, _ := json.Marshal(struct {
+		Names       []string          `json:"repositories"`
+		IDs         []string          `json:"repository_ids"`
+		Permissions map[string]string `json:"permissions"`
+	}{c.config.Repositories.Names, c.config.Repositories.IDs, c.config.Permissions})
+	sum := sha256.Sum256(b)
+	return c.id + ":" + hex.EncodeToString(sum[:8])
+}
+
+// update applies the provided change to the configuration and invalidates the cached token.
+func (c *Config) update(change func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	change()
+	c.gen++
+	c.cached.Store(nil)
+}
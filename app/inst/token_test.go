@@ -0,0 +1,778 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inst
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/beatlabs/github-auth/endpoint"
+	"github.com/beatlabs/github-auth/jwt"
+	"golang.org/x/oauth2"
+)
+
+func TestConfig_TokenSource(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		tok, err := c.TokenSource(context.Background()).Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := tok.AccessToken, "v1.1f699f1069f60xxx"; got != want {
+			t.Errorf("access token = %q; want %q", got, want)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d; want 1", calls)
+	}
+
+	c.SetRepositories([]string{"repo"})
+	_, err = c.TokenSource(context.Background()).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d; want 2 after changing the repositories", calls)
+	}
+}
+
+func TestConfig_TokenStore(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	store := jwt.NewMemoryStore()
+	key := getPrivateKey(t)
+	for i := 0; i < 3; i++ {
+		c, err := NewEnterpriseConfig(ts.URL, "1", "2", key, jwt.WithTokenStore(store))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = c.TokenSource(context.Background()).Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d; want 1", calls)
+	}
+	tok, err := store.Get(context.Background(), "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok == nil {
+		t.Fatal("got no stored token, expected one")
+	}
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", key, jwt.WithTokenStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetRepositories([]string{"repo"})
+	_, err = c.TokenSource(context.Background()).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d; want 2 for a restricted token", calls)
+	}
+}
+
+func TestConfig_EarlyRefresh(t *testing.T) {
+	expiry := time.Date(2050, 1, 1, 11, 12, 13, 0, time.UTC)
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "` + expiry.Format(time.RFC3339) + `"}`))
+	}))
+	defer ts.Close()
+
+	now := expiry.Add(-time.Hour)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t), jwt.WithEarlyRefresh(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		now       time.Time
+		wantCalls int
+	}{
+		{now: expiry.Add(-time.Hour), wantCalls: 1},
+		{now: expiry.Add(-time.Minute - time.Second), wantCalls: 1},
+		{now: expiry.Add(-time.Minute + time.Second), wantCalls: 2},
+	}
+	for _, tt := range tests {
+		now = tt.now
+		if _, err := c.TokenSource(context.Background()).Token(); err != nil {
+			t.Fatal(err)
+		}
+		if calls != tt.wantCalls {
+			t.Errorf("at %v before expiry: calls = %d; want %d", expiry.Sub(tt.now), calls, tt.wantCalls)
+		}
+	}
+}
+
+func TestConfig_SingleFlight(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(200 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const goroutines = 300
+	start := make(chan struct{})
+	errs := make(chan error, goroutines)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := c.TokenSource(context.Background()).Token()
+			errs <- err
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("calls = %d; want 1", got)
+	}
+}
+
+func TestConfig_Client_Cancel(t *testing.T) {
+	var tokenCalls, apiCalls int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/app/installations/2/access_tokens" {
+			atomic.AddInt32(&apiCalls, 1)
+			if got, want := r.Header.Get("Authorization"), "token v1.1f699f1069f60xxx"; got != want {
+				t.Errorf("authorization = %q; want %q", got, want)
+			}
+			return
+		}
+		atomic.AddInt32(&tokenCalls, 1)
+		// hold the token request until the client gives up
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := c.Client(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/v3/installation/repositories", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v; want %v", err, context.DeadlineExceeded)
+	}
+	if got := atomic.LoadInt32(&apiCalls); got != 0 {
+		t.Errorf("api calls = %d; want 0 for the cancelled request", got)
+	}
+
+	// the token fetch goes on and its token is used by the next requests
+	close(release)
+	req, err = http.NewRequestWithContext(context.Background(), http.MethodGet, ts.URL+"/api/v3/installation/repositories", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := atomic.LoadInt32(&apiCalls); got != 1 {
+		t.Errorf("api calls = %d; want 1", got)
+	}
+	if got := atomic.LoadInt32(&tokenCalls); got != 1 {
+		t.Errorf("token calls = %d; want 1", got)
+	}
+}
+
+func TestConfig_Token_LeaderCancel(t *testing.T) {
+	var calls int32
+	started, release := make(chan struct{}), make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		close(started)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		_, err := c.TokenSource(ctx).Token()
+		leader <- err
+	}()
+	<-started
+	waiter := make(chan error, 1)
+	go func() {
+		_, err := c.TokenSource(context.Background()).Token()
+		waiter <- err
+	}()
+
+	// cancelling the caller which started the fetch does not fail the other callers
+	cancel()
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Errorf("leader error = %v; want %v", err, context.Canceled)
+	}
+	close(release)
+	if err := <-waiter; err != nil {
+		t.Errorf("waiter error = %v; want the fetched token", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("calls = %d; want 1", got)
+	}
+}
+
+func TestConfig_Token_WaiterCancel(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go c.TokenSource(context.Background()).Token() //nolint:errcheck
+	<-started
+
+	// a caller waiting for the in-flight fetch of another caller stops once its context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.TokenSource(ctx).Token()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v; want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the waiting caller ignored its context")
+	}
+}
+
+func TestConfig_Token_UpdateDuringFetch(t *testing.T) {
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.TokenSource(context.Background()).Token()
+		done <- err
+	}()
+	<-started
+
+	// the configuration changes while the token is fetched, so the token is not cached
+	c.SetRepositories([]string{"octo-repo"})
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if c.HasValidToken() {
+		t.Error("the token fetched for the previous configuration should not be cached")
+	}
+}
+
+func TestConfig_Suspended(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		w.WriteHeader(http.StatusForbidden)
+		//nolint:errcheck
+		w.Write([]byte(`{"message":"This installation has been suspended","documentation_url":"https://docs.github.com/rest/apps/apps#create-an-installation-access-token-for-an-app","status":"403"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t), jwt.WithRetry(jwt.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.TokenSource(context.Background()).Token()
+	if !errors.Is(err, jwt.ErrInstallationSuspended) {
+		t.Fatalf("error = %v; want %v", err, jwt.ErrInstallationSuspended)
+	}
+	if errors.Is(err, jwt.ErrAppNotInstalled) {
+		t.Errorf("error = %v; should not match %v", err, jwt.ErrAppNotInstalled)
+	}
+	var ae *jwt.AuthError
+	if !errors.As(err, &ae) {
+		t.Fatalf("error = %T; want *jwt.AuthError", err)
+	}
+	if got, want := ae.DocumentationURL, "https://docs.github.com/rest/apps/apps#create-an-installation-access-token-for-an-app"; got != want {
+		t.Errorf("documentation URL = %q; want %q", got, want)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d; want 1, suspended installations are not retried", calls)
+	}
+}
+
+func TestConfig_Expiry(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		expiry, err := c.Expiry(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := time.Date(2050, 1, 1, 11, 12, 13, 0, time.UTC); !expiry.Equal(want) {
+			t.Errorf("expiry = %s; want %s", expiry, want)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d; want 1, the cached token is used", calls)
+	}
+}
+
+func TestConfig_Client_Header(t *testing.T) {
+	var got http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			got = r.Header.Clone()
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t), jwt.WithHeader(http.Header{
+		"X-Proxy-Auth":  {"secret"},
+		"Authorization": {"Basic proxy"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Client(context.Background()).Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if v := got.Get("X-Proxy-Auth"); v != "secret" {
+		t.Errorf("X-Proxy-Auth = %q; want secret", v)
+	}
+	if v := got.Values("Authorization"); len(v) != 1 || v[0] != "token v1.1f699f1069f60xxx" {
+		t.Errorf("Authorization = %q; want the installation token", v)
+	}
+}
+
+func TestConfig_Transport(t *testing.T) {
+	var got http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := &countingTransport{}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: base})
+	client := &http.Client{Transport: &outerTransport{next: c.Transport(ctx)}}
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if v := got.Get("X-Outer"); v != "1" {
+		t.Errorf("X-Outer = %q; want the header of the outer transport", v)
+	}
+	if v := got.Get("Authorization"); v != "token v1.1f699f1069f60xxx" {
+		t.Errorf("Authorization = %q; want the installation token", v)
+	}
+	if base.count != 2 {
+		t.Errorf("requests through the context's transport = %d; want the token and the API requests", base.count)
+	}
+}
+
+func TestConfig_Client_ContextTokenRequests(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.Header().Set("Content-Type", "application/json")
+			//nolint:errcheck
+			w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := &countingTransport{}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: base})
+	client := c.Client(ctx)
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(ts.URL + "/api/v3/installation/repositories")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if want := []string{"/api/v3/app/installations/2/access_tokens"}; !reflect.DeepEqual(base.tokenPaths, want) {
+		t.Errorf("token requests through the context's client = %v; want %v", base.tokenPaths, want)
+	}
+	if base.count != 3 {
+		t.Errorf("requests through the context's client = %d; want 3", base.count)
+	}
+}
+
+// outerTransport sets a header before the inner transport.
+type outerTransport struct {
+	next http.RoundTripper
+}
+
+func (t *outerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("X-Outer", "1")
+	return t.next.RoundTrip(r)
+}
+
+// countingTransport counts the requests sent with the default transport,
+// recording the paths of the token requests.
+type countingTransport struct {
+	count      int
+	tokenPaths []string
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.count++
+	if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+		t.tokenPaths = append(t.tokenPaths, r.URL.Path)
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestConfig_HasValidToken(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+	defer func() { timeNow = time.Now }()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.HasValidToken() {
+		t.Error("HasValidToken = true; want false before any token is fetched")
+	}
+	if _, err := c.TokenSource(context.Background()).Token(); err != nil {
+		t.Fatal(err)
+	}
+	if !c.HasValidToken() {
+		t.Error("HasValidToken = false; want true after a token is fetched")
+	}
+
+	timeNow = func() time.Time { return time.Date(2050, 1, 1, 11, 12, 14, 0, time.UTC) }
+	if c.HasValidToken() {
+		t.Error("HasValidToken = true; want false after the token expiry")
+	}
+	timeNow = time.Now
+
+	c.SetRepositories([]string{"repo"})
+	if c.HasValidToken() {
+		t.Error("HasValidToken = true; want false after changing the repositories")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d; want 1, HasValidToken never fetches tokens", calls)
+	}
+}
+
+func TestConfig_Close(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.TokenSource(context.Background()).Token(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.HasValidToken() {
+		t.Error("HasValidToken = true; want false after Close")
+	}
+	if _, err := c.TokenSource(context.Background()).Token(); !errors.Is(err, ErrClosed) {
+		t.Errorf("error = %v; want %v", err, ErrClosed)
+	}
+	if _, err := c.ScopedToken(context.Background(), ScopeRequest{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("scoped token error = %v; want %v", err, ErrClosed)
+	}
+}
+
+func TestWithClock(t *testing.T) {
+	expiry := time.Date(2050, 1, 1, 11, 12, 13, 0, time.UTC)
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "` + expiry.Format(time.RFC3339) + `"}`))
+	}))
+	defer ts.Close()
+
+	now := expiry.Add(-time.Hour)
+	c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t), jwt.WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.TokenSource(context.Background()).Token(); err != nil {
+		t.Fatal(err)
+	}
+	if !c.HasValidToken() {
+		t.Error("the token should be valid an hour before its expiry")
+	}
+
+	now = expiry.Add(time.Second)
+	if c.HasValidToken() {
+		t.Error("the token should be expired after its expiry")
+	}
+	if _, err := c.TokenSource(context.Background()).Token(); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d; want 2", calls)
+	}
+}
+
+func TestConfig_Client_Unauthorized(t *testing.T) {
+	tests := map[string]struct {
+		rejected   int
+		wantStatus int
+	}{
+		"revoked token": {rejected: 1, wantStatus: http.StatusOK},
+		"always":        {rejected: 3, wantStatus: http.StatusUnauthorized},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var mints, requests int
+			var bodies []string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/app/installations/2/access_tokens" {
+					mints++
+					w.Header().Set("Content-Type", "application/json")
+					//nolint:errcheck
+					w.Write([]byte(`{"token": "v1.token` + strconv.Itoa(mints) + `", "expires_at": "2050-01-01T11:12:13Z"}`))
+					return
+				}
+				requests++
+				b, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Error(err)
+				}
+				bodies = append(bodies, string(b))
+				if requests <= tt.rejected {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				if got, want := r.Header.Get("Authorization"), "token v1.token2"; got != want {
+					t.Errorf("Authorization = %q; want %q", got, want)
+				}
+			}))
+			defer ts.Close()
+
+			e, err := endpoint.NewEnterprise(ts.URL, endpoint.WithRawPath())
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := NewConfigWithEndpoint(e, "1", "2", getPrivateKey(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := c.Client(context.Background()).Post(ts.URL+"/repos/octo/repo/issues", "application/json", strings.NewReader(`{"title":"bug"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d; want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if mints != 2 || requests != 2 {
+				t.Errorf("mints = %d, requests = %d; want a single retry with a new token", mints, requests)
+			}
+			for i, b := range bodies {
+				if b != `{"title":"bug"}` {
+					t.Errorf("body %d = %q; want the request body", i, b)
+				}
+			}
+		})
+	}
+}
+
+// failingStore is a token store failing the operations with a non-nil error.
+type failingStore struct {
+	getErr, setErr error
+}
+
+func (s failingStore) Get(context.Context, string) (*oauth2.Token, error) { return nil, s.getErr }
+
+func (s failingStore) Set(context.Context, string, *oauth2.Token) error { return s.setErr }
+
+func (s failingStore) Delete(context.Context, string) error { return nil }
+
+func TestWithStoreFailureMode(t *testing.T) {
+	errStore := errors.New("connection refused")
+	tests := map[string]struct {
+		store     failingStore
+		mode      jwt.StoreFailureMode
+		wantCalls int
+		wantErr   bool
+	}{
+		"open get":   {store: failingStore{getErr: errStore}, mode: jwt.StoreFailOpen, wantCalls: 1},
+		"open set":   {store: failingStore{setErr: errStore}, mode: jwt.StoreFailOpen, wantCalls: 1},
+		"closed get": {store: failingStore{getErr: errStore}, mode: jwt.StoreFailClosed, wantCalls: 0, wantErr: true},
+		"closed set": {store: failingStore{setErr: errStore}, mode: jwt.StoreFailClosed, wantCalls: 1, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.Header().Set("Content-Type", "application/json")
+				//nolint:errcheck
+				w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+			}))
+			defer ts.Close()
+
+			c, err := NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t), jwt.WithTokenStore(tt.store), jwt.WithStoreFailureMode(tt.mode))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = c.TokenSource(context.Background()).Token()
+			if tt.wantErr {
+				if !errors.Is(err, jwt.ErrTokenStore) || !errors.Is(err, errStore) {
+					t.Errorf("error = %v; want a token store error", err)
+				}
+				if c.HasValidToken() {
+					t.Error("the token should not be cached")
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d; want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
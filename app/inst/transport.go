@@ -0,0 +1,105 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inst
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// transport adds the installation token to the requests.
+// The token is refreshed using the context of each request,
+// so that a cancelled request stops waiting for its token refresh,
+// and the HTTP client of the transport's context, if any.
+// Requests rejected with a 401 response are retried once with a new token,
+// unless their body cannot be replayed (GetBody is nil).
+type transport struct {
+	conf *Config
+	base http.RoundTripper
+	hc   *http.Client
+}
+
+// newTransport returns a transport using the HTTP client of the context
+// for both the token fetches and the requests.
+func newTransport(ctx context.Context, conf *Config) *transport {
+	hc, _ := ctx.Value(oauth2.HTTPClient).(*http.Client)
+	return &transport{conf: conf, base: contextTransport(ctx), hc: hc}
+}
+
+// contextTransport returns the transport of the context's HTTP client,
+// as set with the oauth2.HTTPClient context key, or http.DefaultTransport.
+func contextTransport(ctx context.Context) http.RoundTripper {
+	if hc, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok && hc != nil && hc.Transport != nil {
+		return hc.Transport
+	}
+	return http.DefaultTransport
+}
+
+// tokenContext returns the context of the request with the HTTP client of the transport, if any,
+// to fetch the tokens with.
+func (t *transport) tokenContext(r *http.Request) context.Context {
+	if t.hc == nil {
+		return r.Context()
+	}
+	return context.WithValue(r.Context(), oauth2.HTTPClient, t.hc)
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx := t.tokenContext(r)
+	token, err := t.conf.token(ctx)
+	if err != nil {
+		if r.Body != nil {
+			r.Body.Close()
+		}
+		return nil, err
+	}
+	resp, err := t.conf.roundTrip(t.base, r, token)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || (r.Body != nil && r.GetBody == nil) {
+		return resp, err
+	}
+
+	// the token was rejected before its expiry, e.g. revoked after a permissions change,
+	// so the request is retried exactly once with a new token
+	retry := r.Clone(r.Context())
+	if r.Body != nil {
+		if retry.Body, err = r.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	token, err = t.conf.refresh(ctx, token)
+	if err != nil {
+		if retry.Body != nil {
+			retry.Body.Close()
+		}
+		return resp, nil
+	}
+	resp.Body.Close()
+	return t.conf.roundTrip(t.base, retry, token)
+}
+
+// roundTrip sends the request with the extra headers and the provided token.
+func (c *Config) roundTrip(base http.RoundTripper, r *http.Request, token *oauth2.Token) (*http.Response, error) {
+	// a RoundTripper must not modify the provided request
+	r = r.Clone(r.Context())
+	for k, vv := range c.config.Header {
+		// the request headers take precedence over the extra headers
+		if k = http.CanonicalHeaderKey(k); k != "Authorization" && len(r.Header.Values(k)) == 0 {
+			r.Header[k] = append([]string(nil), vv...)
+		}
+	}
+	token.SetAuthHeader(r)
+	start := time.Now()
+	resp, err := base.RoundTrip(r)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	c.config.ObserveRequest(status, time.Since(start))
+	c.config.Log().Debug("installation request", "method", r.Method, "path", r.URL.Path, "status", status)
+	return resp, err
+}
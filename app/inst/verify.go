@@ -0,0 +1,79 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inst
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/beatlabs/github-auth/endpoint"
+	"github.com/beatlabs/github-auth/internal/api"
+)
+
+// TokenStatus is the status of an installation token, as returned by VerifyToken.
+type TokenStatus struct {
+	// Valid reports whether GitHub accepts the token.
+	Valid bool
+
+	// Expiry is the expiry of the token, from the GitHub-Authentication-Token-Expiration header,
+	// or zero if GitHub does not return it.
+	Expiry time.Time
+}
+
+// Remaining returns the remaining lifetime of the token at now,
+// or zero if the token is not valid, is expired or its expiry is not known.
+func (s TokenStatus) Remaining(now time.Time) time.Duration {
+	if !s.Valid || s.Expiry.IsZero() || !now.Before(s.Expiry) {
+		return 0
+	}
+	return s.Expiry.Sub(now)
+}
+
+// expirationLayouts are the layouts of the GitHub-Authentication-Token-Expiration header, in order.
+var expirationLayouts = []string{"2006-01-02 15:04:05 MST", "2006-01-02 15:04:05 -0700", time.RFC3339}
+
+// VerifyToken checks whether GitHub accepts an installation token, e.g. received from the service
+// which minted it, with a cheap GET /installation/repositories?per_page=1 request to the endpoint,
+// or to the default endpoint if nil. The request uses the HTTP client of the context,
+// as set with the oauth2.HTTPClient context key.
+// A rejected token is reported as not valid without error; the other failures are returned.
+func VerifyToken(ctx context.Context, e *endpoint.Endpoint, token string) (TokenStatus, error) {
+	if e == nil {
+		var err error
+		if e, err = endpoint.New(); err != nil {
+			return TokenStatus{}, err
+		}
+	}
+	url, err := e.Get("/installation/repositories?per_page=1")
+	if err != nil {
+		return TokenStatus{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return TokenStatus{}, err
+	}
+	req.Header.Set("Authorization", "token "+token)
+	resp, _, err := api.Do(&http.Client{Transport: contextTransport(ctx)}, req)
+	if err != nil {
+		var ae *api.Error
+		if errors.As(err, &ae) && ae.StatusCode == http.StatusUnauthorized {
+			return TokenStatus{}, nil
+		}
+		return TokenStatus{}, fmt.Errorf("failed to verify token: %w", err)
+	}
+	status := TokenStatus{Valid: true}
+	if raw := resp.Header.Get("GitHub-Authentication-Token-Expiration"); raw != "" {
+		for _, layout := range expirationLayouts {
+			if t, err := time.Parse(layout, raw); err == nil {
+				status.Expiry = t
+				break
+			}
+		}
+	}
+	return status, nil
+}
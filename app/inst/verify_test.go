@@ -0,0 +1,76 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inst
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/beatlabs/github-auth/endpoint"
+)
+
+func TestVerifyToken(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/installation/repositories" || r.URL.Query().Get("per_page") != "1" {
+			t.Errorf("request = %s; want /installation/repositories?per_page=1", r.URL)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Header.Get("Authorization") {
+		case "token ghs_valid":
+			w.Header().Set("GitHub-Authentication-Token-Expiration", "2050-01-01 11:12:13 UTC")
+		case "token ghs_noexpiry":
+		case "token ghs_error":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			//nolint:errcheck
+			w.Write([]byte(`{"message": "Bad credentials"}`))
+			return
+		}
+		//nolint:errcheck
+		w.Write([]byte(`{"total_count": 1, "repositories": [{"id": 1}]}`))
+	}))
+	defer ts.Close()
+
+	e, err := endpoint.NewEnterprise(ts.URL, endpoint.WithRawPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiry := time.Date(2050, 1, 1, 11, 12, 13, 0, time.UTC)
+
+	tests := map[string]struct {
+		token   string
+		want    TokenStatus
+		wantErr bool
+	}{
+		"valid":     {token: "ghs_valid", want: TokenStatus{Valid: true, Expiry: expiry}},
+		"no expiry": {token: "ghs_noexpiry", want: TokenStatus{Valid: true}},
+		"revoked":   {token: "ghs_revoked"},
+		"error":     {token: "ghs_error", wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := VerifyToken(context.Background(), e, tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v; want error %t", err, tt.wantErr)
+			}
+			if got.Valid != tt.want.Valid || !got.Expiry.Equal(tt.want.Expiry) {
+				t.Errorf("status = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+
+	status := TokenStatus{Valid: true, Expiry: expiry}
+	if got := status.Remaining(expiry.Add(-time.Hour)); got != time.Hour {
+		t.Errorf("remaining = %v; want 1h", got)
+	}
+	if got := status.Remaining(expiry.Add(time.Second)); got != 0 {
+		t.Errorf("remaining = %v; want 0 after the expiry", got)
+	}
+}
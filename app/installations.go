@@ -0,0 +1,101 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/beatlabs/github-auth/app/inst"
+	"github.com/beatlabs/github-auth/internal/api"
+)
+
+// Installation is an installation of the App as returned by the API.
+// See: https://docs.github.com/en/rest/apps/apps#list-installations-for-the-authenticated-app
+type Installation struct {
+	ID                  int64             `json:"id"`
+	Account             Account           `json:"account"`
+	TargetType          string            `json:"target_type"`
+	RepositorySelection string            `json:"repository_selection"`
+	Permissions         map[string]string `json:"permissions"`
+	SuspendedAt         *time.Time        `json:"suspended_at"`
+}
+
+// installationsPerPage is the maximum page size of the installations list.
+const installationsPerPage = 100
+
+// ListInstallations returns all the installations of the App.
+func (c *Config) ListInstallations(ctx context.Context) ([]Installation, error) {
+	var installations []Installation
+	err := c.installations(ctx, func(page []Installation) error {
+		installations = append(installations, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list installations: %w", err)
+	}
+	return installations, nil
+}
+
+// InstallationSummary is the high-level view of the installations of the App.
+type InstallationSummary struct {
+	// Count is the number of installations, including the suspended ones.
+	Count int
+
+	// Accounts are the accounts the App is installed on, in the order of the installations.
+	Accounts []Account
+}
+
+// InstallationSummary returns the number of installations of the App and their accounts.
+func (c *Config) InstallationSummary(ctx context.Context) (*InstallationSummary, error) {
+	summary := &InstallationSummary{}
+	err := c.installations(ctx, func(page []Installation) error {
+		for _, i := range page {
+			summary.Accounts = append(summary.Accounts, i.Account)
+		}
+		summary.Count += len(page)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list installations: %w", err)
+	}
+	return summary, nil
+}
+
+// ForEachInstallation pages through the installations of the App and calls fn
+// with the Installation Config of each, stopping at the first error returned by fn.
+// Suspended installations are included; their token requests fail with jwt.ErrInstallationSuspended.
+func (c *Config) ForEachInstallation(ctx context.Context, fn func(*inst.Config) error) error {
+	return c.installations(ctx, func(page []Installation) error {
+		for _, i := range page {
+			install, err := c.InstallationConfig(strconv.FormatInt(i.ID, 10))
+			if err != nil {
+				return err
+			}
+			if err := fn(install); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// installations pages through the installations of the App and calls fn with each page.
+func (c *Config) installations(ctx context.Context, fn func([]Installation) error) error {
+	url, err := c.config.Endpoint.Get(fmt.Sprintf("/app/installations?per_page=%d", installationsPerPage))
+	if err != nil {
+		return err
+	}
+	return api.Pages(ctx, c.Client(), url, func(body []byte) error {
+		var page []Installation
+		if err := json.Unmarshal(body, &page); err != nil {
+			return err
+		}
+		return fn(page)
+	})
+}
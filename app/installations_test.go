@@ -0,0 +1,132 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/beatlabs/github-auth/app/inst"
+)
+
+func newInstallationsServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/app/installations":
+			if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+				t.Errorf("Authorization = %q; want a JWT", r.Header.Get("Authorization"))
+			}
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("Link", `<`+ts.URL+`/api/v3/app/installations?per_page=100&page=2>; rel="next"`)
+				//nolint:errcheck
+				w.Write([]byte(`[{"id": 1, "account": {"id": 10, "login": "octo", "type": "Organization"}, "target_type": "Organization", "repository_selection": "all"}]`))
+				return
+			}
+			//nolint:errcheck
+			w.Write([]byte(`[{"id": 2, "account": {"id": 20, "login": "cat", "type": "User"}, "target_type": "User", "suspended_at": "2024-01-01T00:00:00Z"}]`))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/access_tokens"):
+			id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v3/app/installations/"), "/access_tokens")
+			//nolint:errcheck
+			fmt.Fprintf(w, `{"token": "token-%s", "expires_at": "2050-01-01T11:12:13Z"}`, id)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	return ts
+}
+
+func TestConfig_ListInstallations(t *testing.T) {
+	ts := newInstallationsServer(t)
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	installations, err := c.ListInstallations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(installations) != 2 {
+		t.Fatalf("installations = %d; want 2", len(installations))
+	}
+	if i := installations[0]; i.ID != 1 || i.Account.Login != "octo" || i.SuspendedAt != nil {
+		t.Errorf("installation = %+v; want the active octo installation", i)
+	}
+	if i := installations[1]; i.ID != 2 || i.Account.Login != "cat" || i.SuspendedAt == nil {
+		t.Errorf("installation = %+v; want the suspended cat installation", i)
+	}
+}
+
+func TestConfig_InstallationSummary(t *testing.T) {
+	ts := newInstallationsServer(t)
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary, err := c.InstallationSummary(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &InstallationSummary{
+		Count: 2,
+		Accounts: []Account{
+			{ID: 10, Login: "octo", Type: "Organization"},
+			{ID: 20, Login: "cat", Type: "User"},
+		},
+	}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("summary = %+v; want %+v", summary, want)
+	}
+}
+
+func TestConfig_ForEachInstallation(t *testing.T) {
+	ts := newInstallationsServer(t)
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "1", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tokens []string
+	err = c.ForEachInstallation(context.Background(), func(install *inst.Config) error {
+		token, err := install.TokenSource(context.Background()).Token()
+		if err != nil {
+			return err
+		}
+		tokens = append(tokens, token.AccessToken)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(tokens, ","), "token-1,token-2"; got != want {
+		t.Errorf("tokens = %s; want %s", got, want)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = c.ForEachInstallation(context.Background(), func(install *inst.Config) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("error = %v; want %v", err, stop)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d; want 1", calls)
+	}
+}
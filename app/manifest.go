@@ -0,0 +1,103 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/beatlabs/github-auth/endpoint"
+	"github.com/beatlabs/github-auth/internal/api"
+	"github.com/beatlabs/github-auth/jwt"
+	"github.com/beatlabs/github-auth/key"
+	"golang.org/x/oauth2"
+)
+
+// Manifest is the GitHub App created from a manifest, with its credentials.
+// The credentials are only returned once by GitHub, so they should be stored securely.
+// See: https://docs.github.com/en/apps/sharing-github-apps/registering-a-github-app-from-a-manifest
+type Manifest struct {
+	App
+	ClientID      string `json:"client_id"`
+	ClientSecret  string `json:"client_secret"`
+	WebhookSecret string `json:"webhook_secret"`
+
+	// PEM is the PEM encoded private key of the App.
+	PEM string `json:"pem"`
+
+	// PrivateKey is the parsed private key of the App.
+	PrivateKey *rsa.PrivateKey `json:"-"`
+
+	endpoint *endpoint.Endpoint
+}
+
+// ConvertManifest completes the GitHub App manifest flow by exchanging the temporary code
+// GitHub redirected with for the created App and its credentials.
+// The code expires after an hour and can be used only once.
+// The HTTP client from the context is used.
+func ConvertManifest(ctx context.Context, code string) (*Manifest, error) {
+	endpoint, err := endpoint.New()
+	if err != nil {
+		return nil, err
+	}
+
+	return convertManifest(ctx, endpoint, code)
+}
+
+// ConvertEnterpriseManifest completes the GitHub Enterprise App manifest flow, see ConvertManifest.
+// The /api/v3 path is appended to bare host URLs, see endpoint.NewEnterprise.
+func ConvertEnterpriseManifest(ctx context.Context, url, code string) (*Manifest, error) {
+	endpoint, err := endpoint.NewEnterprise(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertManifest(ctx, endpoint, code)
+}
+
+func convertManifest(ctx context.Context, endpoint *endpoint.Endpoint, code string) (*Manifest, error) {
+	if code == "" {
+		return nil, fmt.Errorf("failed to convert manifest: code is empty")
+	}
+	u, err := endpoint.Get(fmt.Sprintf("/app-manifests/%s/conversions", url.PathEscape(code)))
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	_, body, err := api.Do(oauth2.NewClient(ctx, nil), req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert manifest: %w", err)
+	}
+	m := &Manifest{endpoint: endpoint}
+	if err := json.Unmarshal(body, m); err != nil {
+		return nil, fmt.Errorf("failed to convert manifest: %w", err)
+	}
+	m.PrivateKey, err = key.Parse([]byte(m.PEM))
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert manifest: %w", err)
+	}
+	return m, nil
+}
+
+// Config returns the Config of the App created from the manifest,
+// on the GitHub endpoint the manifest was converted with.
+func (m *Manifest) Config(opts ...jwt.Option) (*Config, error) {
+	e := m.endpoint
+	if e == nil {
+		var err error
+		if e, err = endpoint.New(); err != nil {
+			return nil, err
+		}
+	}
+	return new(e, strconv.FormatInt(m.ID, 10), m.PrivateKey, opts)
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/beatlabs/github-auth/internal/api"
+)
+
+func TestConvertEnterpriseManifest(t *testing.T) {
+	key := getPrivateKey(t)
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v3/app-manifests/abc123/conversions" {
+			w.WriteHeader(http.StatusNotFound)
+			//nolint:errcheck
+			w.Write([]byte(`{"message": "Not Found"}`))
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("Authorization = %q; want none", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		//nolint:errcheck
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":             42,
+			"slug":           "octoapp",
+			"name":           "Octoapp",
+			"client_id":      "Iv1.8a61f9b3a7aba766",
+			"client_secret":  "1726be1638095a19edd134c77bde3aa2ece1e5d8",
+			"webhook_secret": "e340154128314309424b7c8e90325147d99fdafa",
+			"pem":            string(pemKey),
+		})
+	}))
+	defer ts.Close()
+
+	m, err := ConvertEnterpriseManifest(context.Background(), ts.URL, "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != 42 || m.Slug != "octoapp" || m.ClientID != "Iv1.8a61f9b3a7aba766" {
+		t.Errorf("manifest = %+v; want the octoapp App", m)
+	}
+	if got, want := m.ClientSecret, "1726be1638095a19edd134c77bde3aa2ece1e5d8"; got != want {
+		t.Errorf("client secret = %q; want %q", got, want)
+	}
+	if got, want := m.WebhookSecret, "e340154128314309424b7c8e90325147d99fdafa"; got != want {
+		t.Errorf("webhook secret = %q; want %q", got, want)
+	}
+	if !m.PrivateKey.Equal(key) {
+		t.Error("private key does not match the PEM")
+	}
+
+	c, err := m.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.config.AppID, "42"; got != want {
+		t.Errorf("app ID = %q; want %q", got, want)
+	}
+	if got, want := c.config.Endpoint.String(), ts.URL+"/api/v3"; got != want {
+		t.Errorf("endpoint = %q; want %q", got, want)
+	}
+
+	_, err = ConvertEnterpriseManifest(context.Background(), ts.URL, "expired")
+	var ae *api.Error
+	if !errors.As(err, &ae) || ae.StatusCode != http.StatusNotFound {
+		t.Errorf("error = %v; want a 404", err)
+	}
+}
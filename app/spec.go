@@ -0,0 +1,39 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"crypto/rsa"
+
+	"github.com/beatlabs/github-auth/endpoint"
+	"github.com/beatlabs/github-auth/jwt"
+)
+
+// Spec is the JSON serializable part of an App config, without the private key,
+// e.g. for declarative configuration files which do not embed secrets.
+type Spec struct {
+	AppID string `json:"app_id"`
+
+	// URL is the GitHub API URL, e.g. https://github.example.com/api/v3.
+	// If empty, the default GitHub API URL is used.
+	URL string `json:"url,omitempty"`
+}
+
+// Spec returns the JSON serializable part of the config, without the private key.
+func (c *Config) Spec() Spec {
+	return Spec{AppID: c.config.AppID, URL: c.config.Endpoint.String()}
+}
+
+// NewConfigFromSpec returns a new App config from the spec and the provided private key.
+func NewConfigFromSpec(s Spec, key *rsa.PrivateKey, opts ...jwt.Option) (*Config, error) {
+	if s.URL == "" {
+		return NewConfig(s.AppID, key, opts...)
+	}
+	e, err := endpoint.NewEnterprise(s.URL, endpoint.WithRawPath())
+	if err != nil {
+		return nil, err
+	}
+	return new(e, s.AppID, key, opts)
+}
@@ -0,0 +1,50 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConfig_Spec(t *testing.T) {
+	tests := map[string]struct {
+		url  string
+		want string
+	}{
+		"github":     {want: `{"app_id":"1","url":"https://api.github.com"}`},
+		"enterprise": {url: "https://github.example.com", want: `{"app_id":"1","url":"https://github.example.com/api/v3"}`},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c, err := NewConfig("1", getPrivateKey(t))
+			if tt.url != "" {
+				c, err = NewEnterpriseConfig(tt.url, "1", getPrivateKey(t))
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := json.Marshal(c.Spec())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("spec = %s; want %s", b, tt.want)
+			}
+
+			var s Spec
+			if err := json.Unmarshal(b, &s); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := NewConfigFromSpec(s, getPrivateKey(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded.Spec() != c.Spec() {
+				t.Errorf("loaded spec = %+v; want %+v", loaded.Spec(), c.Spec())
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package user implements GitHub App user-to-server authentication,
+// to act on behalf of a user who authorized the App with the web application flow.
+//
+// See: https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/generating-a-user-access-token-for-a-github-app
+package user
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/beatlabs/github-auth/endpoint"
+	"golang.org/x/oauth2"
+)
+
+// Config defines a GitHub App user-to-server config.
+type Config struct {
+	config oauth2.Config
+}
+
+func new(endpoint *endpoint.Endpoint, clientID, clientSecret string) (*Config, error) {
+	if clientID == "" {
+		return nil, errors.New("user: client ID is empty")
+	}
+	web := endpoint.WebURL().String()
+	return &Config{
+		config: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   web + "/login/oauth/authorize",
+				TokenURL:  web + "/login/oauth/access_token",
+				AuthStyle: oauth2.AuthStyleInParams,
+			},
+		},
+	}, nil
+}
+
+// NewConfig returns a new user-to-server config with the App's client ID and secret.
+func NewConfig(clientID, clientSecret string) (*Config, error) {
+	endpoint, err := endpoint.New()
+	if err != nil {
+		return nil, err
+	}
+
+	return new(endpoint, clientID, clientSecret)
+}
+
+// NewEnterpriseConfig returns a new GitHub Enterprise user-to-server config.
+// The OAuth flow is served by the web URL of the endpoint, see endpoint.Endpoint.WebURL.
+func NewEnterpriseConfig(url, clientID, clientSecret string) (*Config, error) {
+	endpoint, err := endpoint.NewEnterprise(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(endpoint, clientID, clientSecret)
+}
+
+// SetRedirectURL sets the URL the users are redirected to after authorizing the App.
+// If empty, the callback URL of the App is used.
+func (c *Config) SetRedirectURL(url string) {
+	c.config.RedirectURL = url
+}
+
+// AuthCodeURL returns the URL the users authorize the App at.
+// The state protects against CSRF and must be checked when the users are redirected back.
+func (c *Config) AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string {
+	return c.config.AuthCodeURL(state, opts...)
+}
+
+// Exchange exchanges the code the users are redirected back with for a user access token.
+// If the App expires user tokens, the token has a refresh token and an expiry.
+// The HTTP client from the context is used.
+func (c *Config) Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	return c.config.Exchange(ctx, code, opts...)
+}
+
+// TokenSource returns a token source which reuses the user access token
+// and refreshes it with its refresh token when expired.
+// Refreshed tokens replace the refresh token, so the latest token should be persisted
+// for the next sessions, e.g. by comparing the returned tokens.
+// The provided context is used to refresh the token.
+func (c *Config) TokenSource(ctx context.Context, t *oauth2.Token) oauth2.TokenSource {
+	return c.config.TokenSource(ctx, t)
+}
+
+// Client returns an HTTP client wrapping the context's
+// HTTP transport and adding Authorization headers with the user access token,
+// which is refreshed when expired.
+//
+// The returned client and its Transport should not be modified.
+func (c *Config) Client(ctx context.Context, t *oauth2.Token) *http.Client {
+	return oauth2.NewClient(ctx, c.TokenSource(ctx, t))
+}
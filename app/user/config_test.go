@@ -0,0 +1,105 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package user
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestConfig_Exchange(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/login/oauth/access_token" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := r.Form.Get("client_id")+":"+r.Form.Get("client_secret"), "Iv1.abc:secret"; got != want {
+			t.Errorf("client credentials = %q; want %q", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Form.Get("grant_type") {
+		case "authorization_code":
+			if got, want := r.Form.Get("code"), "code1"; got != want {
+				t.Errorf("code = %q; want %q", got, want)
+			}
+			//nolint:errcheck
+			w.Write([]byte(`{"access_token": "ghu_1", "token_type": "bearer", "expires_in": 1, "refresh_token": "ghr_1"}`))
+		case "refresh_token":
+			if got, want := r.Form.Get("refresh_token"), "ghr_1"; got != want {
+				t.Errorf("refresh token = %q; want %q", got, want)
+			}
+			//nolint:errcheck
+			w.Write([]byte(`{"access_token": "ghu_2", "token_type": "bearer", "expires_in": 28800, "refresh_token": "ghr_2"}`))
+		default:
+			t.Errorf("unexpected grant type %q", r.Form.Get("grant_type"))
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewEnterpriseConfig(ts.URL, "Iv1.abc", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetRedirectURL("https://example.com/callback")
+
+	u, err := url.Parse(c.AuthCodeURL("state1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u.Scheme+"://"+u.Host+u.Path, ts.URL+"/login/oauth/authorize"; got != want {
+		t.Errorf("auth URL = %q; want %q", got, want)
+	}
+	if got, want := u.Query().Get("redirect_uri"), "https://example.com/callback"; got != want {
+		t.Errorf("redirect URI = %q; want %q", got, want)
+	}
+
+	token, err := c.Exchange(context.Background(), "code1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := token.AccessToken, "ghu_1"; got != want {
+		t.Errorf("access token = %q; want %q", got, want)
+	}
+
+	// the token expires within the expiry delta of the token source
+	token.Expiry = time.Now().Add(-time.Second)
+	refreshed, err := c.TokenSource(context.Background(), token).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := refreshed.AccessToken, "ghu_2"; got != want {
+		t.Errorf("refreshed access token = %q; want %q", got, want)
+	}
+	if got, want := refreshed.RefreshToken, "ghr_2"; got != want {
+		t.Errorf("refreshed refresh token = %q; want %q", got, want)
+	}
+}
+
+func TestNewConfig(t *testing.T) {
+	c, err := NewConfig("Iv1.abc", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := oauth2.Endpoint{
+		AuthURL:   "https://github.com/login/oauth/authorize",
+		TokenURL:  "https://github.com/login/oauth/access_token",
+		AuthStyle: oauth2.AuthStyleInParams,
+	}
+	if c.config.Endpoint != want {
+		t.Errorf("endpoint = %+v; want %+v", c.config.Endpoint, want)
+	}
+
+	if _, err := NewConfig("", "secret"); err == nil {
+		t.Error("expected an error for the empty client ID")
+	}
+}
@@ -1,17 +1,46 @@
 package endpoint
 
 import (
+	"fmt"
 	"net/url"
+	"os"
+	"path"
+	"strings"
 )
 
 var (
 	// Default is the default GitHub api endpoint.
+	// Changing it is not safe while configs are created concurrently;
+	// to use another endpoint, e.g. a test server, prefer the NewConfigWithEndpoint constructors.
 	Default = "https://api.github.com"
+
+	// EnvAPIURL is the environment variable holding the GitHub API URL.
+	EnvAPIURL = "GITHUB_API_URL"
+
+	// EnterprisePath is the GitHub Enterprise Server REST API path.
+	EnterprisePath = "/api/v3"
 )
 
+// Option configures an Endpoint.
+type Option func(*options)
+
+type options struct {
+	rawPath bool
+}
+
+// WithRawPath disables appending EnterprisePath to bare host enterprise URLs.
+func WithRawPath() Option {
+	return func(o *options) {
+		o.rawPath = true
+	}
+}
+
 // Endpoint holds the GitHub API endpoint URL.
 type Endpoint struct {
 	url *url.URL
+
+	// appended reports whether EnterprisePath was appended to a bare host URL.
+	appended bool
 }
 
 func new(raw string) (*Endpoint, error) {
@@ -27,16 +56,107 @@ func New() (*Endpoint, error) {
 	return new(Default)
 }
 
+// NewFromEnv returns a new endpoint with the API URL from the GITHUB_API_URL
+// environment variable, as set by GitHub Actions.
+// The default GitHub API URL is used if the variable is unset or empty.
+func NewFromEnv() (*Endpoint, error) {
+	raw := os.Getenv(EnvAPIURL)
+	if raw == "" {
+		return New()
+	}
+	return NewEnterprise(raw, WithRawPath())
+}
+
 // NewEnterprise returns a new endpoint with the provided GitHub Enterprise API URL.
-func NewEnterprise(url string) (*Endpoint, error) {
-	return new(url)
+// The URL must be absolute with an http or https scheme.
+//
+// If the URL is a bare host (e.g. https://github.example.com), EnterprisePath is appended
+// (e.g. https://github.example.com/api/v3), unless WithRawPath is provided.
+func NewEnterprise(url string, opts ...Option) (*Endpoint, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	e, err := new(url)
+	if err != nil {
+		return nil, err
+	}
+	if s := e.url.Scheme; s != "http" && s != "https" {
+		return nil, fmt.Errorf("invalid enterprise URL %q: scheme must be http or https", url)
+	}
+	if e.url.Host == "" {
+		return nil, fmt.Errorf("invalid enterprise URL %q: host is empty", url)
+	}
+	if !o.rawPath && strings.Trim(e.url.Path, "/") == "" {
+		e.url.Path = EnterprisePath
+		e.appended = true
+	}
+	return e, nil
+}
+
+// Raw returns the endpoint without the EnterprisePath appended to a bare host URL,
+// as if WithRawPath had been provided to NewEnterprise, or e if none was appended.
+func (e *Endpoint) Raw() *Endpoint {
+	if !e.appended {
+		return e
+	}
+	u := e.URL()
+	u.Path = ""
+	return &Endpoint{url: u}
 }
 
 // Get returns the full GitHub api endpoint for the provided uri.
+// The uri is joined to the endpoint URL path; it cannot override
+// the endpoint scheme and host or escape its path.
 func (e *Endpoint) Get(uri string) (string, error) {
-	u, err := url.ParseRequestURI(uri)
+	ref, err := url.Parse(uri)
 	if err != nil {
 		return "", err
 	}
-	return e.url.ResolveReference(u).String(), nil
+	if ref.IsAbs() || ref.Host != "" {
+		return "", fmt.Errorf("invalid uri %q: must be relative to the endpoint", uri)
+	}
+	base := path.Clean("/" + e.url.Path)
+	joined := path.Join(base, ref.Path)
+	if joined != base && !strings.HasPrefix(joined, strings.TrimSuffix(base, "/")+"/") {
+		return "", fmt.Errorf("invalid uri %q: escapes the endpoint path", uri)
+	}
+	u := *e.url
+	u.Path = joined
+	u.RawPath = ""
+	u.RawQuery = ref.RawQuery
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+// String returns the endpoint URL.
+func (e *Endpoint) String() string {
+	return e.url.String()
+}
+
+// URL returns a copy of the endpoint URL.
+func (e *Endpoint) URL() *url.URL {
+	u := *e.url
+	if e.url.User != nil {
+		user := *e.url.User
+		u.User = &user
+	}
+	return &u
+}
+
+// WebURL returns the GitHub web URL of the endpoint, which serves the OAuth flow,
+// e.g. https://github.com for https://api.github.com and
+// https://github.example.com for https://github.example.com/api/v3.
+func (e *Endpoint) WebURL() *url.URL {
+	u := e.URL()
+	p := strings.TrimSuffix(u.Path, "/")
+	switch {
+	case p == "" && strings.HasPrefix(u.Host, "api."):
+		u.Host = strings.TrimPrefix(u.Host, "api.")
+	case strings.HasSuffix(p, EnterprisePath):
+		p = strings.TrimSuffix(p, EnterprisePath)
+	}
+	u.Path = p
+	u.RawPath = ""
+	return u
 }
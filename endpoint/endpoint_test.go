@@ -0,0 +1,175 @@
+package endpoint
+
+import (
+	"testing"
+)
+
+func TestNewEnterprise(t *testing.T) {
+	tests := map[string]struct {
+		url     string
+		wantErr bool
+	}{
+		"https":          {url: "https://github.example.com/api/v3"},
+		"http":           {url: "http://github.example.com/api/v3"},
+		"missing scheme": {url: "github.example.com", wantErr: true},
+		"invalid scheme": {url: "ftp://github.example.com", wantErr: true},
+		"missing host":   {url: "https:///api/v3", wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewEnterprise(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v; want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewEnterprise_APIPath(t *testing.T) {
+	tests := map[string]struct {
+		url  string
+		opts []Option
+		want string
+	}{
+		"bare host":       {url: "https://github.example.com", want: "https://github.example.com/api/v3"},
+		"bare host slash": {url: "https://github.example.com/", want: "https://github.example.com/api/v3"},
+		"api path":        {url: "https://github.example.com/api/v3", want: "https://github.example.com/api/v3"},
+		"raw path":        {url: "https://github.example.com", opts: []Option{WithRawPath()}, want: "https://github.example.com"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			e, err := NewEnterprise(tt.url, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := e.String(); got != tt.want {
+				t.Errorf("url = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpoint_Raw(t *testing.T) {
+	tests := map[string]struct {
+		url  string
+		want string
+	}{
+		"bare host": {url: "https://github.example.com", want: "https://github.example.com"},
+		"api path":  {url: "https://github.example.com/api/v3", want: "https://github.example.com/api/v3"},
+		"proxy":     {url: "https://proxy.example.com/github", want: "https://proxy.example.com/github"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			e, err := NewEnterprise(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := e.Raw().String(); got != tt.want {
+				t.Errorf("url = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpoint_Get(t *testing.T) {
+	e, err := NewEnterprise("https://github.example.com/api/v3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]struct {
+		uri     string
+		want    string
+		wantErr bool
+	}{
+		"path":          {uri: "/app/installations/1/access_tokens", want: "https://github.example.com/api/v3/app/installations/1/access_tokens"},
+		"relative path": {uri: "app", want: "https://github.example.com/api/v3/app"},
+		"query":         {uri: "/installation/repositories?per_page=100", want: "https://github.example.com/api/v3/installation/repositories?per_page=100"},
+		"full URL":      {uri: "https://evil.example.com/app", wantErr: true},
+		"host":          {uri: "//evil.example.com/app", wantErr: true},
+		"escape":        {uri: "/../../app", wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := e.Get(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v; want error %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpoint_Get_Default(t *testing.T) {
+	e, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := e.Get("/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://api.github.com/app"; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestEndpoint_URL(t *testing.T) {
+	e, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := e.URL()
+	u.Host = "evil.example.com"
+	if got, want := e.String(), Default; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	tests := map[string]struct {
+		env  string
+		want string
+	}{
+		"unset":      {want: Default},
+		"github.com": {env: "https://api.github.com", want: "https://api.github.com"},
+		"enterprise": {env: "https://github.example.com/api/v3", want: "https://github.example.com/api/v3"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(EnvAPIURL, tt.env)
+			e, err := NewFromEnv()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := e.String(); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpoint_WebURL(t *testing.T) {
+	tests := map[string]struct {
+		url  string
+		want string
+	}{
+		"default":     {url: Default, want: "https://github.com"},
+		"data region": {url: "https://api.octocorp.ghe.com", want: "https://octocorp.ghe.com"},
+		"enterprise":  {url: "https://github.example.com/api/v3", want: "https://github.example.com"},
+		"prefix":      {url: "https://example.com/github/api/v3/", want: "https://example.com/github"},
+		"raw path":    {url: "https://github.example.com/custom", want: "https://github.example.com/custom"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			e, err := NewEnterprise(tt.url, WithRawPath())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := e.WebURL().String(); got != tt.want {
+				t.Errorf("web URL = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
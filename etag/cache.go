@@ -0,0 +1,70 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package etag
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// Entry is a cached response.
+type Entry struct {
+	// ETag is the entity tag of the response.
+	ETag string
+
+	// StatusCode is the status code of the response.
+	StatusCode int
+
+	// Header is the header of the response.
+	Header http.Header
+
+	// Body is the body of the response.
+	Body []byte
+}
+
+// Cache stores the responses with an ETag.
+// The responses are keyed by request URL.
+//
+// Implementations must be safe for concurrent use.
+// Responses contain the data the client has access to, so caches should not be
+// shared by clients with different credentials.
+type Cache interface {
+	// Get returns the entry cached for the key, or nil if there is none.
+	Get(ctx context.Context, key string) (*Entry, error)
+
+	// Set caches the entry for the key.
+	Set(ctx context.Context, key string, entry *Entry) error
+}
+
+// MemoryCache is an in-memory Cache.
+//
+// The entries are never evicted, so the memory used grows with the number of
+// distinct URLs requested and the size of their responses. It suits clients
+// polling a bounded set of URLs; other clients should use a bounded Cache.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]*Entry
+}
+
+// NewMemoryCache returns a new in-memory Cache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]*Entry)}
+}
+
+// Get returns the entry cached for the key, or nil if there is none.
+func (c *MemoryCache) Get(_ context.Context, key string) (*Entry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries[key], nil
+}
+
+// Set caches the entry for the key.
+func (c *MemoryCache) Set(_ context.Context, key string, entry *Entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = entry
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package etag implements an HTTP transport caching the GitHub API responses by ETag.
+//
+// The cached ETags are sent in the If-None-Match header of the following requests
+// and the cached responses are served when GitHub replies 304 Not Modified.
+// Conditional requests answered with 304 do not count against the primary rate limit.
+//
+// See: https://docs.github.com/en/rest/using-the-rest-api/best-practices-for-using-the-rest-api#use-conditional-requests-if-appropriate
+package etag
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Transport is an http.RoundTripper caching the responses of GET requests by ETag.
+type Transport struct {
+	// Base is the transport sending the requests.
+	// If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	// Cache stores the responses.
+	// If nil, a MemoryCache is used.
+	Cache Cache
+
+	memOnce sync.Once
+	mem     *MemoryCache
+}
+
+// NewTransport returns a Transport caching the responses of base in cache.
+// If cache is nil, a MemoryCache is used.
+func NewTransport(base http.RoundTripper, cache Cache) *Transport {
+	if cache == nil {
+		cache = NewMemoryCache()
+	}
+	return &Transport{Base: base, Cache: cache}
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+func (t *Transport) cache() Cache {
+	if t.Cache != nil {
+		return t.Cache
+	}
+	t.memOnce.Do(func() { t.mem = NewMemoryCache() })
+	return t.mem
+}
+
+// RoundTrip sends the request, adding the If-None-Match header of a cached response.
+// Cache failures are ignored and the request is sent unconditionally.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Method != http.MethodGet || r.Header.Get("If-None-Match") != "" || r.Header.Get("Range") != "" {
+		return t.base().RoundTrip(r)
+	}
+	key := r.URL.String()
+	entry, err := t.cache().Get(r.Context(), key)
+	if err == nil && entry != nil {
+		// a RoundTripper must not modify the provided request
+		r = r.Clone(r.Context())
+		r.Header.Set("If-None-Match", entry.ETag)
+	}
+	resp, err := t.base().RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotModified && entry != nil:
+		resp.Body.Close()
+		return cachedResponse(r, resp, entry), nil
+	case resp.StatusCode == http.StatusOK && resp.Header.Get("ETag") != "":
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		//nolint:errcheck
+		t.cache().Set(r.Context(), key, &Entry{ // the response is still returned
+			ETag:       resp.Header.Get("ETag"),
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+			Body:       body,
+		})
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return resp, nil
+	}
+	return resp, nil
+}
+
+// cachedResponse returns the cached response of a 304 Not Modified response.
+// The headers of the 304 response, such as the rate limit ones, replace the cached ones.
+func cachedResponse(r *http.Request, notModified *http.Response, entry *Entry) *http.Response {
+	header := entry.Header.Clone()
+	for k, v := range notModified.Header {
+		if k != "Content-Length" {
+			header[k] = v
+		}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.StatusCode, http.StatusText(entry.StatusCode)),
+		StatusCode:    entry.StatusCode,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       r,
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package etag
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	calls, notModified := 0, 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.Header().Set("X-RateLimit-Remaining", "4999")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("X-RateLimit-Remaining", "5000")
+		//nolint:errcheck
+		w.Write([]byte(`{"id": 1}`))
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: NewTransport(nil, nil)}
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(ts.URL + "/app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("status = %d; want %d", resp.StatusCode, http.StatusOK)
+		}
+		if got, want := string(body), `{"id": 1}`; got != want {
+			t.Errorf("body = %s; want %s", got, want)
+		}
+		if i > 0 {
+			if got, want := resp.Header.Get("X-RateLimit-Remaining"), "4999"; got != want {
+				t.Errorf("X-RateLimit-Remaining = %q; want %q from the 304 response", got, want)
+			}
+		}
+	}
+	if calls != 3 || notModified != 2 {
+		t.Errorf("calls = %d, not modified = %d; want 3 and 2", calls, notModified)
+	}
+}
+
+func TestTransport_ZeroValue(t *testing.T) {
+	notModified := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		//nolint:errcheck
+		w.Write([]byte(`{"id": 1}`))
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: &Transport{}}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(ts.URL + "/app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if notModified != 1 {
+		t.Errorf("not modified = %d; want the second request served from the memory cache", notModified)
+	}
+}
+
+func TestTransport_NotCached(t *testing.T) {
+	tests := map[string]struct {
+		method string
+		etag   string
+		status int
+	}{
+		"no etag":    {method: http.MethodGet, status: http.StatusOK},
+		"post":       {method: http.MethodPost, etag: `"v1"`, status: http.StatusOK},
+		"failure":    {method: http.MethodGet, etag: `"v1"`, status: http.StatusInternalServerError},
+		"no content": {method: http.MethodGet, etag: `"v1"`, status: http.StatusNoContent},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("If-None-Match"); got != "" {
+					t.Errorf("If-None-Match = %q; want none", got)
+				}
+				if tt.etag != "" {
+					w.Header().Set("ETag", tt.etag)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer ts.Close()
+
+			cache := NewMemoryCache()
+			client := &http.Client{Transport: NewTransport(nil, cache)}
+			for i := 0; i < 2; i++ {
+				req, err := http.NewRequest(tt.method, ts.URL, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp, err := client.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+			}
+			if len(cache.entries) != 0 {
+				t.Errorf("cache entries = %d; want 0", len(cache.entries))
+			}
+		})
+	}
+}
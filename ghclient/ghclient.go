@@ -0,0 +1,34 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ghclient provides github.com/google/go-github clients
+// authenticated as GitHub App Installations.
+//
+// It is a separate module so that the go-github dependency
+// is only required when the package is used.
+package ghclient
+
+import (
+	"context"
+	"strings"
+
+	"github.com/beatlabs/github-auth/app/inst"
+	"github.com/beatlabs/github-auth/endpoint"
+	"github.com/google/go-github/v74/github"
+)
+
+// New returns a go-github client authenticated as the provided Installation.
+// The Installation's GitHub Enterprise endpoint is used when configured.
+func New(ctx context.Context, install *inst.Config) (*github.Client, error) {
+	client := github.NewClient(install.Client(ctx))
+
+	e := install.Endpoint()
+	if e == nil || e.String() == endpoint.Default {
+		return client, nil
+	}
+	base := e.URL()
+	upload := e.URL()
+	upload.Path = strings.TrimSuffix(strings.TrimSuffix(upload.Path, "/"), endpoint.EnterprisePath)
+	return client.WithEnterpriseURLs(base.String(), upload.String())
+}
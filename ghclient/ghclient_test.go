@@ -0,0 +1,81 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ghclient
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/beatlabs/github-auth/app/inst"
+)
+
+func TestNew(t *testing.T) {
+	install, err := inst.NewConfig("1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := New(context.Background(), install)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := client.BaseURL.String(), "https://api.github.com/"; got != want {
+		t.Errorf("base URL = %q; want %q", got, want)
+	}
+}
+
+func TestNew_Enterprise(t *testing.T) {
+	var auth string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v3/app/installations/2/access_tokens":
+			//nolint:errcheck
+			w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+		case "/api/v3/installation/repositories":
+			auth = r.Header.Get("Authorization")
+			//nolint:errcheck
+			w.Write([]byte(`{"total_count": 0, "repositories": []}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	install, err := inst.NewEnterpriseConfig(ts.URL, "1", "2", getPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := New(context.Background(), install)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := client.BaseURL.String(), ts.URL+"/api/v3/"; got != want {
+		t.Errorf("base URL = %q; want %q", got, want)
+	}
+	if got, want := client.UploadURL.String(), ts.URL+"/api/uploads/"; got != want {
+		t.Errorf("upload URL = %q; want %q", got, want)
+	}
+
+	_, _, err = client.Apps.ListRepos(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := auth, "token v1.1f699f1069f60xxx"; got != want {
+		t.Errorf("authorization = %q; want %q", got, want)
+	}
+}
+
+func getPrivateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
@@ -0,0 +1,16 @@
+module github.com/beatlabs/github-auth/ghclient
+
+go 1.23.0
+
+require (
+	github.com/beatlabs/github-auth v0.0.0-00010101000000-000000000000
+	github.com/google/go-github/v74 v74.0.0
+)
+
+require (
+	github.com/google/go-querystring v1.1.0 // indirect
+	golang.org/x/oauth2 v0.21.0 // indirect
+	golang.org/x/sync v0.7.0 // indirect
+)
+
+replace github.com/beatlabs/github-auth => ../
@@ -0,0 +1,101 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package githubtest_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/beatlabs/github-auth/app/inst"
+	"github.com/beatlabs/github-auth/githubtest"
+	"github.com/beatlabs/github-auth/jwt"
+)
+
+func TestServer(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := inst.NewEnterpriseConfig(s.URL, "1", "2", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetRepositories([]string{"repo"})
+	token, err := c.TokenSource(context.Background()).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := token.AccessToken, githubtest.DefaultToken; got != want {
+		t.Errorf("access token = %q; want %q", got, want)
+	}
+	if d := time.Until(token.Expiry); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("expiry = %s; want in an hour", token.Expiry)
+	}
+	selection, err := c.RepositorySelection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := selection, "selected"; got != want {
+		t.Errorf("repository selection = %q; want %q", got, want)
+	}
+
+	reqs := s.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("requests = %d; want 1", len(reqs))
+	}
+	if got, want := reqs[0].InstallationID, "2"; got != want {
+		t.Errorf("installation ID = %q; want %q", got, want)
+	}
+	if got := reqs[0].Repositories; len(got) != 1 || got[0] != "repo" {
+		t.Errorf("repositories = %v; want [repo]", got)
+	}
+}
+
+func TestServer_SetError(t *testing.T) {
+	s := githubtest.NewServer()
+	defer s.Close()
+	s.SetError(http.StatusForbidden, "This installation has been suspended")
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := inst.NewEnterpriseConfig(s.URL, "1", "3", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.TokenSource(context.Background()).Token()
+	if !errors.Is(err, jwt.ErrInstallationSuspended) {
+		t.Errorf("error = %v; want %v", err, jwt.ErrInstallationSuspended)
+	}
+
+	s.SetError(0, "")
+	s.SetToken("ghs_other", time.Hour)
+	token, err := c.TokenSource(context.Background()).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := token.AccessToken, "ghs_other"; got != want {
+		t.Errorf("access token = %q; want %q", got, want)
+	}
+}
+
+func TestTokenSource(t *testing.T) {
+	token, err := githubtest.TokenSource("ghs_static").Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !token.Valid() || token.AccessToken != "ghs_static" {
+		t.Errorf("token = %+v; want a valid ghs_static token", token)
+	}
+}
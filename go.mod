@@ -2,4 +2,7 @@ module github.com/beatlabs/github-auth
 
 go 1.21
 
-require golang.org/x/oauth2 v0.21.0
+require (
+	golang.org/x/oauth2 v0.21.0
+	golang.org/x/sync v0.7.0
+)
@@ -0,0 +1,57 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package api implements the GitHub REST API requests shared by the App and Installation configs.
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/beatlabs/github-auth/jwt"
+)
+
+// Error is returned when an API request fails.
+type Error struct {
+	StatusCode int
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// maxBodySize is the maximum size of the response bodies read.
+const maxBodySize = 1 << 20
+
+// Do sends the request with the provided client and returns the response body.
+// An *Error is returned if the response status is not 2xx,
+// and jwt.ErrResponseTooLarge if the body is larger than the maximum size read.
+func Do(client *http.Client, req *http.Request) (*http.Response, []byte, error) {
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if c := resp.StatusCode; c < 200 || c > 299 {
+		e := &Error{StatusCode: c}
+		//nolint:errcheck
+		json.Unmarshal(body, e) // the error body is optional
+		return nil, nil, e
+	}
+	if len(body) > maxBodySize {
+		return nil, nil, fmt.Errorf("%w: response larger than %d bytes", jwt.ErrResponseTooLarge, maxBodySize)
+	}
+	return resp, body, nil
+}
@@ -0,0 +1,46 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/beatlabs/github-auth/jwt"
+)
+
+func TestDo_BodySize(t *testing.T) {
+	tests := map[string]struct {
+		size    int
+		wantErr error
+	}{
+		"max size":        {size: maxBodySize},
+		"larger than max": {size: maxBodySize + 1, wantErr: jwt.ErrResponseTooLarge},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				//nolint:errcheck
+				w.Write(bytes.Repeat([]byte("a"), tt.size))
+			}))
+			defer ts.Close()
+
+			req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, body, err := Do(ts.Client(), req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v; want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && len(body) != tt.size {
+				t.Errorf("body size = %d; want %d", len(body), tt.size)
+			}
+		})
+	}
+}
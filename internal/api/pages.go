@@ -0,0 +1,84 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Pages sends GET requests starting at the provided URL and following the rel="next" links
+// of the responses, and calls fn with the body of each page.
+// The page size is set with the per_page query parameter of the URL, which GitHub
+// carries over to the next links.
+// The iteration stops at the first error, either of a request or of fn.
+//
+// See: https://docs.github.com/en/rest/using-the-rest-api/using-pagination-in-the-rest-api
+func Pages(ctx context.Context, client *http.Client, url string, fn func(body []byte) error) error {
+	for url != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, body, err := Do(client, req)
+		if err != nil {
+			return err
+		}
+		if err := fn(body); err != nil {
+			return err
+		}
+		url, err = next(req.URL, resp.Header)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// next returns the rel="next" URL of the Link header, or an empty string on the last page.
+// The next URL must be on the same host as the current one,
+// so that the credentials of the client are not sent elsewhere.
+func next(current *url.URL, h http.Header) (string, error) {
+	for _, link := range h.Values("Link") {
+		for _, l := range strings.Split(link, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(l), ";")
+			target = strings.TrimSpace(target)
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") || !isNext(params) {
+				continue
+			}
+			u, err := current.Parse(strings.Trim(target, "<>"))
+			if err != nil {
+				return "", fmt.Errorf("invalid next page link %q: %w", target, err)
+			}
+			if u.Scheme != current.Scheme || u.Host != current.Host {
+				return "", fmt.Errorf("invalid next page link %q: must be on %s", target, current.Host)
+			}
+			if u.String() == current.String() {
+				return "", fmt.Errorf("invalid next page link %q: same as the current page", target)
+			}
+			return u.String(), nil
+		}
+	}
+	return "", nil
+}
+
+// isNext reports whether the Link parameters contain rel="next".
+func isNext(params string) bool {
+	for _, p := range strings.Split(params, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+			continue
+		}
+		for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(value), `"`)) {
+			if rel == "next" {
+				return true
+			}
+		}
+	}
+	return false
+}
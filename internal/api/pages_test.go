@@ -0,0 +1,130 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestPages(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Query().Get("per_page"), "2"; got != want {
+			t.Errorf("per_page = %q; want %q", got, want)
+		}
+		switch page := r.URL.Query().Get("page"); page {
+		case "":
+			w.Header().Set("Link", `<`+ts.URL+`/items?per_page=2&page=2>; rel="next", <`+ts.URL+`/items?per_page=2&page=3>; rel="last"`)
+		case "2":
+			w.Header().Set("Link", `</items?per_page=2&page=3>; rel="next", </items?per_page=2&page=1>; rel="prev"`)
+		case "3":
+			w.Header().Set("Link", `</items?per_page=2&page=2>; rel="prev"`)
+		}
+		//nolint:errcheck
+		fmt.Fprintf(w, "page %s", r.URL.Query().Get("page"))
+	}))
+	defer ts.Close()
+
+	var pages []string
+	err := Pages(context.Background(), ts.Client(), ts.URL+"/items?per_page=2", func(body []byte) error {
+		pages = append(pages, string(body))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(pages), "[page  page 2 page 3]"; got != want {
+		t.Errorf("pages = %s; want %s", got, want)
+	}
+}
+
+func TestPages_Error(t *testing.T) {
+	calls := 0
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Link", `<`+ts.URL+`/items?page=2>; rel="next"`)
+		if r.URL.Query().Get("page") == "2" {
+			w.WriteHeader(http.StatusNotFound)
+			//nolint:errcheck
+			w.Write([]byte(`{"message": "Not Found"}`))
+		}
+	}))
+	defer ts.Close()
+
+	err := Pages(context.Background(), ts.Client(), ts.URL+"/items", func(body []byte) error { return nil })
+	var ae *Error
+	if !errors.As(err, &ae) || ae.StatusCode != http.StatusNotFound {
+		t.Fatalf("error = %v; want a 404 *Error", err)
+	}
+	if got, want := err.Error(), "404 Not Found: Not Found"; got != want {
+		t.Errorf("error = %q; want %q", got, want)
+	}
+
+	calls = 0
+	stop := errors.New("stop")
+	err = Pages(context.Background(), ts.Client(), ts.URL+"/items", func(body []byte) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("error = %v; want %v", err, stop)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d; want 1", calls)
+	}
+}
+
+func TestNext(t *testing.T) {
+	current, _ := url.Parse("https://api.github.com/installation/repositories?per_page=100")
+	tests := map[string]struct {
+		link string
+		want string
+		err  bool
+	}{
+		"missing": {},
+		"last page": {
+			link: `<https://api.github.com/installation/repositories?page=1>; rel="prev", <https://api.github.com/installation/repositories?page=1>; rel="first"`,
+		},
+		"next": {
+			link: `<https://api.github.com/installation/repositories?per_page=100&page=2>; rel="next", <https://api.github.com/installation/repositories?per_page=100&page=5>; rel="last"`,
+			want: "https://api.github.com/installation/repositories?per_page=100&page=2",
+		},
+		"relative": {
+			link: `</installation/repositories?page=2>; rel="next"`,
+			want: "https://api.github.com/installation/repositories?page=2",
+		},
+		"multiple relations": {
+			link: `<https://api.github.com/installation/repositories?page=2>; rel="next last"`,
+			want: "https://api.github.com/installation/repositories?page=2",
+		},
+		"other host": {
+			link: `<https://example.com/installation/repositories?page=2>; rel="next"`,
+			err:  true,
+		},
+		"same page": {
+			link: `<https://api.github.com/installation/repositories?per_page=100>; rel="next"`,
+			err:  true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := http.Header{}
+			if tt.link != "" {
+				h.Set("Link", tt.link)
+			}
+			got, err := next(current, h)
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v; want error %t", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("next = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
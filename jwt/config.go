@@ -14,12 +14,18 @@ package jwt
 import (
 	"bytes"
 	"context"
+	"crypto/rsa"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/beatlabs/github-auth/endpoint"
 	"golang.org/x/oauth2"
 )
 
@@ -27,7 +33,8 @@ import (
 type Config struct {
 	JWT
 
-	// Repositories is the list of repositories to limit the token access to.
+	// Repositories is the list of repositories to limit the token access to,
+	// MaxRepositories at most in total.
 	Repositories struct {
 		// Names is the list of repository Names.
 		Names []string `json:"repositories,omitempty"`
@@ -36,15 +43,183 @@ type Config struct {
 		IDs []string `json:"repository_ids,omitempty"`
 	}
 
+	// Permissions is the optional subset of the App's permissions to limit the token access to.
+	// See: https://docs.github.com/en/rest/apps/apps#create-an-installation-access-token-for-an-app
+	Permissions map[string]string
+
+	// Endpoint is the GitHub API endpoint the TokenURL was derived from.
+	// It is used to build the URLs of other App API calls.
+	Endpoint *endpoint.Endpoint
+
 	// TokenURL is the GitHub App Installation URL for creating access tokens.
 	// See: https://docs.github.com/en/free-pro-team@latest/rest/reference/apps#create-an-installation-access-token-for-an-app
 	TokenURL string
+
+	// HTTPClient optionally specifies the HTTP client used to fetch tokens.
+	// If nil, the HTTP client from the context is used.
+	HTTPClient *http.Client
+
+	// RequestTimeout optionally limits the duration of each token request, including reading the response.
+	// A shorter deadline of the caller's context takes precedence.
+	// If zero, the token requests are only limited by the caller's context and the HTTP client.
+	RequestTimeout time.Duration
+
+	// Retry optionally configures the retries of failed token requests.
+	Retry RetryPolicy
+
+	// ResponseHook is optionally called with the status code and the body of each token response,
+	// successful or not, e.g. to debug unexpected permissions. The body of the successful responses
+	// contains the token: the hook is meant for debugging only and must not log it in production.
+	// The body must not be modified.
+	ResponseHook func(statusCode int, body []byte)
+
+	// Store optionally specifies where the Installation configs share their tokens.
+	Store TokenStore
+
+	// StoreFailureMode specifies how the Installation configs handle the failures of the Store.
+	// The zero value is StoreFailOpen.
+	StoreFailureMode StoreFailureMode
+
+	// EarlyRefresh optionally specifies how long before their expiry the tokens are refreshed.
+	// If zero, DefaultEarlyRefresh is used.
+	EarlyRefresh time.Duration
+
+	// Logger optionally specifies the logger of the token requests.
+	Logger Logger
+
+	// Tracer optionally specifies the tracer of the token requests.
+	Tracer Tracer
+
+	// Metrics optionally specifies the metrics recorder of the token operations.
+	Metrics Metrics
+
+	// InstallationID is the optional installation ID the tokens are fetched for.
+	// It is used to annotate logs and traces.
+	InstallationID string
 }
 
 // TokenSource returns a JWT TokenSource using the configuration
 // in c and the HTTP client from the provided context.
 func (c *Config) TokenSource(ctx context.Context) oauth2.TokenSource {
-	return oauth2.ReuseTokenSource(nil, jwtSource{ctx, c})
+	return oauth2.ReuseTokenSourceWithExpiry(nil, c.source(ctx), c.RefreshBuffer())
+}
+
+// DefaultEarlyRefresh is the default time before their expiry the tokens are refreshed.
+const DefaultEarlyRefresh = 10 * time.Second
+
+// DefaultFallbackExpiry is the lifetime of the tokens returned without expiry,
+// when the JWT expiry is not set either.
+const DefaultFallbackExpiry = 10 * time.Minute
+
+// fallbackExpiry returns the lifetime of the tokens returned without expiry:
+// the JWT expiry, which is shorter than the GitHub token lifetime, or DefaultFallbackExpiry.
+func (c *Config) fallbackExpiry() time.Duration {
+	if c.Expires > 0 {
+		return c.Expires
+	}
+	return DefaultFallbackExpiry
+}
+
+// RefreshBuffer returns how long before their expiry the tokens are refreshed.
+func (c *Config) RefreshBuffer() time.Duration {
+	if c.EarlyRefresh <= 0 {
+		return DefaultEarlyRefresh
+	}
+	return c.EarlyRefresh
+}
+
+// Validate checks that the fields required to fetch a token are set and valid,
+// without any network call.
+func (c *Config) Validate() error {
+	if err := c.JWT.Validate(); err != nil {
+		return err
+	}
+	if c.TokenURL == "" {
+		return errors.New("jwt: token URL is empty")
+	}
+	u, err := url.Parse(c.TokenURL)
+	if err != nil {
+		return fmt.Errorf("jwt: invalid token URL %q: %w", c.TokenURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("jwt: invalid token URL %q: scheme must be http or https", c.TokenURL)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("jwt: invalid token URL %q: host is empty", c.TokenURL)
+	}
+	if n := len(c.Repositories.Names) + len(c.Repositories.IDs); n > MaxRepositories {
+		return fmt.Errorf("%w: %d repositories requested, GitHub accepts at most %d per token", ErrTooManyRepositories, n, MaxRepositories)
+	}
+	if _, err := repositoryIDs(c.Repositories.IDs); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MaxRepositories is the maximum number of repositories, by name or ID,
+// GitHub accepts in a token request.
+// See: https://docs.github.com/en/rest/apps/apps#create-an-installation-access-token-for-an-app
+const MaxRepositories = 500
+
+// PermissionRequest returns the permissions requested for the tokens,
+// with the single_file access level split from the other permissions.
+func (c *Config) PermissionRequest() PermissionRequest {
+	level, ok := c.Permissions[singleFile]
+	if !ok {
+		return PermissionRequest{Permissions: c.Permissions}
+	}
+	pp := make(map[string]string, len(c.Permissions)-1)
+	for name, l := range c.Permissions {
+		if name != singleFile {
+			pp[name] = l
+		}
+	}
+	return PermissionRequest{Permissions: pp, SingleFile: level}
+}
+
+// SetPermissionRequest sets the permissions requested for the tokens.
+func (c *Config) SetPermissionRequest(p PermissionRequest) {
+	c.Permissions = p.permissions()
+}
+
+// Token fetches a new token using the configuration in c
+// and the HTTP client from the provided context.
+func (c *Config) Token(ctx context.Context) (*oauth2.Token, error) {
+	return c.source(ctx).Token()
+}
+
+// source returns a jwtSource with the request body encoded once,
+// as the configuration is not expected to change for the lifetime of the source.
+func (c *Config) source(ctx context.Context) jwtSource {
+	if err := c.Validate(); err != nil {
+		return jwtSource{ctx: ctx, conf: c, err: err}
+	}
+	ids, err := repositoryIDs(c.Repositories.IDs)
+	if err != nil {
+		return jwtSource{ctx: ctx, conf: c, err: err}
+	}
+	body, err := json.Marshal(tokenRequest{
+		Names:       c.Repositories.Names,
+		IDs:         ids,
+		Permissions: c.Permissions,
+	})
+	return jwtSource{ctx: ctx, conf: c, body: body, err: err}
+}
+
+// repositoryIDs converts the repository IDs to the numbers GitHub expects.
+func repositoryIDs(ids []string) ([]int64, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	nn := make([]int64, len(ids))
+	for i, id := range ids {
+		n, err := strconv.ParseInt(strings.TrimSpace(id), 10, 64)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("jwt: invalid repository ID %q", id)
+		}
+		nn[i] = n
+	}
+	return nn, nil
 }
 
 // Client returns an HTTP client wrapping the context's
@@ -56,68 +231,208 @@ func (c *Config) Client(ctx context.Context) *http.Client {
 	return oauth2.NewClient(ctx, c.TokenSource(ctx))
 }
 
+// maxResponseSize is the maximum size of the token responses read.
+const maxResponseSize = 1 << 20
+
+// tokenRequest is the JSON request body.
+type tokenRequest struct {
+	Names       []string          `json:"repositories,omitempty"`
+	IDs         []int64           `json:"repository_ids,omitempty"`
+	Permissions map[string]string `json:"permissions,omitempty"`
+}
+
 // jwtSource is a source that always does a signed JWT request for a token.
 // It should typically be wrapped with a reuseTokenSource.
 type jwtSource struct {
 	ctx  context.Context
 	conf *Config
+	body []byte
+	err  error
 }
 
-func (js jwtSource) Token() (*oauth2.Token, error) {
-	hc := oauth2.NewClient(js.ctx, nil)
-	repos := new(bytes.Buffer)
-	err := json.NewEncoder(repos).Encode(js.conf.Repositories)
-	if err != nil {
-		return nil, err
+func (js jwtSource) Token() (token *oauth2.Token, err error) {
+	ctx, span := js.conf.tracer().Start(js.ctx, TokenSpanName)
+	span.SetAttributes("github.app_id", js.conf.AppID, "github.installation_id", js.conf.InstallationID)
+	attempt, status := 1, 0
+	start := time.Now()
+	defer func() {
+		result := ResultSuccess
+		if err != nil {
+			result = ResultError
+		}
+		js.conf.Recorder().ObserveToken(js.conf.InstallationID, result, status, time.Since(start))
+		span.SetAttributes("http.status_code", status, "github.retry_count", attempt-1)
+		span.End(err)
+	}()
+
+	log := js.conf.Log()
+	if id := CorrelationID(ctx); id != "" {
+		span.SetAttributes("github.correlation_id", id)
+		log = valuesLogger{l: log, kv: []interface{}{"correlation_id", id}}
+	}
+	begin := js.conf.now()
+	resigned := false
+	for ; ; attempt++ {
+		log.Debug("fetching token", "url", js.conf.TokenURL, "attempt", attempt)
+		canResign := !resigned && js.conf.Retry.enabled() && attempt < js.conf.Retry.MaxAttempts
+		token, status, err = js.mint(ctx, canResign)
+		if err == nil {
+			log.Debug("fetched token", "url", js.conf.TokenURL, "attempt", attempt, "expiry", token.Expiry)
+			return token, nil
+		}
+		resign := canResign && undecodableJWT(err)
+		if !js.conf.Retry.enabled() || attempt >= js.conf.Retry.MaxAttempts || !(resign || retryable(err)) {
+			log.Error("failed to fetch token", "url", js.conf.TokenURL, "attempt", attempt, "status", status, "request_id", requestID(err), "error", err)
+			return nil, err
+		}
+		if resign {
+			// the cached JWT may be expired for GitHub, e.g. on clock skew, so a new one is signed once
+			resigned = true
+			js.conf.forgetPayloads()
+			log.Info("JWT could not be decoded, retrying with a new JWT", "url", js.conf.TokenURL, "attempt", attempt, "request_id", requestID(err))
+			continue
+		}
+		d := js.conf.Retry.wait(attempt, err, js.conf.now())
+		if js.conf.Retry.exceeds(js.conf.now().Sub(begin), d) {
+			log.Error("failed to fetch token within the maximum elapsed time", "url", js.conf.TokenURL, "attempt", attempt, "status", status, "request_id", requestID(err), "error", err)
+			return nil, err
+		}
+		var rle *RateLimitError
+		if errors.As(err, &rle) {
+			log.Info("token request rate limited, backing off", "url", js.conf.TokenURL, "attempt", attempt, "reset", rle.Reset, "delay", d)
+		} else {
+			log.Info("retrying token request", "url", js.conf.TokenURL, "attempt", attempt, "status", status, "request_id", requestID(err), "delay", d)
+		}
+		if sleep(ctx, d) != nil {
+			return nil, err
+		}
+	}
+}
+
+// mint fetches a token with a JWT signed with the private key,
+// then with each of the fallback keys while GitHub rejects the JWT.
+// If resign is set, a JWT which could not be decoded is not retried with the fallback keys,
+// as it is re-signed with the same key first, see undecodableJWT.
+func (js jwtSource) mint(ctx context.Context, resign bool) (*oauth2.Token, int, error) {
+	token, status, err := js.fetch(ctx, js.conf.Key())
+	for i, key := range js.conf.FallbackKeys {
+		if status != http.StatusUnauthorized || (resign && undecodableJWT(err)) {
+			break
+		}
+		js.conf.Log().Info("token request unauthorized, retrying with fallback key", "url", js.conf.TokenURL, "key", i+1)
+		token, status, err = js.fetch(ctx, key)
+	}
+	return token, status, err
+}
+
+// fetch does a single request for a token, with a JWT signed with the provided key.
+// It returns the HTTP status code of the response, or 0 if there was none.
+func (js jwtSource) fetch(ctx context.Context, key *rsa.PrivateKey) (*oauth2.Token, int, error) {
+	hc := js.conf.HTTPClient
+	if hc == nil {
+		hc = oauth2.NewClient(ctx, nil)
+	}
+	if js.err != nil {
+		return nil, 0, js.err
 	}
-	req, err := http.NewRequest(http.MethodPost, js.conf.TokenURL, nil)
+	if t := js.conf.RequestTimeout; t > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, js.conf.TokenURL, bytes.NewReader(js.body))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	js.conf.addHeaders(req.Header)
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", mediaType)
+	}
+	if req.Header.Get("X-GitHub-Api-Version") == "" {
+		req.Header.Set("X-GitHub-Api-Version", js.conf.apiVersion())
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", js.conf.userAgent())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if id := CorrelationID(ctx); id != "" {
+		req.Header.Set(CorrelationIDHeader, id)
 	}
-	req.Header.Add("Accept", "application/vnd.github.v3+json")
-	payload, err := js.conf.Payload()
+	payload, err := js.conf.sign(key)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	req.Header.Add("Authorization", "Bearer "+payload)
+	req.Header.Set("Authorization", "Bearer "+payload)
 	resp, err := hc.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("oauth2: cannot fetch token: %v", err)
+		return nil, 0, &tokenError{kind: ErrTokenFetch, err: err}
 	}
 	defer resp.Body.Close()
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	// one more byte is read to detect the truncated responses
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
 	if err != nil {
-		return nil, fmt.Errorf("oauth2: cannot fetch token: %v", err)
+		return nil, resp.StatusCode, &tokenError{kind: ErrTokenFetch, err: err}
+	}
+	if js.conf.ResponseHook != nil {
+		js.conf.ResponseHook(resp.StatusCode, body)
 	}
 	if c := resp.StatusCode; c < 200 || c > 299 {
-		return nil, &oauth2.RetrieveError{
-			Response: resp,
-			Body:     body,
+		err := newAuthError(resp, body)
+		if rateLimited(resp) {
+			return nil, resp.StatusCode, &RateLimitError{Reset: rateLimitReset(resp, js.conf.now()), Err: err}
 		}
+		return nil, resp.StatusCode, err
+	}
+	if len(body) > maxResponseSize {
+		return nil, resp.StatusCode, &tokenError{kind: ErrResponseTooLarge, err: fmt.Errorf("response larger than %d bytes", maxResponseSize)}
 	}
 	// tokenRes is the JSON response body.
 	var tokenRes struct {
 		AccessToken string `json:"token"`
 		ExpiresAt   string `json:"expires_at"`
-		TokenType   string
+		TokenType   string `json:"token_type"`
 	}
 	if err := json.Unmarshal(body, &tokenRes); err != nil {
-		return nil, fmt.Errorf("oauth2: cannot fetch token: %v", err)
+		return nil, resp.StatusCode, &tokenError{kind: ErrTokenParse, err: err}
 	}
 	token := &oauth2.Token{
 		AccessToken: tokenRes.AccessToken,
-		TokenType:   "token",
+		TokenType:   tokenRes.TokenType,
+	}
+	if token.TokenType == "" {
+		token.TokenType = "token"
 	}
 	raw := make(map[string]interface{})
 	//nolint:errcheck
 	json.Unmarshal(body, &raw) // no error checks for optional fields
+	if rl, ok := parseRateLimit(resp.Header); ok {
+		raw[rateLimitExtra] = rl
+	}
 	token = token.WithExtra(raw)
 
-	if tokenRes.ExpiresAt != "" {
-		token.Expiry, err = time.Parse(time.RFC3339, tokenRes.ExpiresAt)
-		if err != nil {
-			return nil, fmt.Errorf("oauth2: cannot fetch token: %v", err)
+	if tokenRes.ExpiresAt == "" {
+		// a zero expiry would make the token reused forever
+		token.Expiry = js.conf.now().Add(js.conf.fallbackExpiry())
+		return token, resp.StatusCode, nil
+	}
+	token.Expiry, err = parseExpiry(tokenRes.ExpiresAt)
+	if err != nil {
+		return nil, resp.StatusCode, &tokenError{kind: ErrExpiryParse, err: err}
+	}
+	return token, resp.StatusCode, nil
+}
+
+// expiryLayouts are the accepted layouts of the token expiry, in order.
+var expiryLayouts = []string{time.RFC3339, time.RFC3339Nano}
+
+// parseExpiry parses the expires_at field of a token response.
+func parseExpiry(raw string) (time.Time, error) {
+	var err error
+	for _, layout := range expiryLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, raw); err == nil {
+			return t, nil
 		}
 	}
-	return token, nil
+	return time.Time{}, fmt.Errorf("invalid expires_at %q: %w", raw, err)
 }
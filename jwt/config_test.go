@@ -6,11 +6,18 @@ package jwt
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/rsa"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/beatlabs/github-auth/key"
 	"golang.org/x/oauth2"
@@ -89,6 +96,168 @@ func TestJWTFetch_JSONResponse(t *testing.T) {
 	}
 }
 
+func TestJWTFetch_TokenType(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "token_type": "bearer", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	conf := &Config{
+		JWT: JWT{
+			AppID:      "1",
+			PrivateKey: getPrivateKey(t),
+		},
+		TokenURL: ts.URL,
+	}
+	tok, err := conf.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tok.TokenType, "bearer"; got != want {
+		t.Errorf("token type = %q; want %q", got, want)
+	}
+}
+
+func TestJWTFetch_Repositories(t *testing.T) {
+	var got struct {
+		Names []string `json:"repositories"`
+		IDs   []int64  `json:"repository_ids"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding the request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	conf := &Config{
+		JWT: JWT{
+			AppID:      "1",
+			PrivateKey: getPrivateKey(t),
+		},
+		TokenURL: ts.URL,
+	}
+	conf.Repositories.Names = []string{"octo-repo"}
+	conf.Repositories.IDs = []string{"1296269"}
+	if _, err := conf.TokenSource(context.Background()).Token(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Names, []string{"octo-repo"}) || !reflect.DeepEqual(got.IDs, []int64{1296269}) {
+		t.Errorf("requested repositories = %v, %v; want [octo-repo], [1296269]", got.Names, got.IDs)
+	}
+}
+
+func TestJWTFetch_RequestBody(t *testing.T) {
+	tests := map[string]struct {
+		opts []Option
+		want string
+	}{
+		"no restriction": {
+			want: `{}`,
+		},
+		"repositories": {
+			opts: []Option{func(c *Config) {
+				c.Repositories.Names = []string{"octo-repo"}
+				c.Repositories.IDs = []string{"1296269"}
+			}},
+			want: `{"repositories":["octo-repo"],"repository_ids":[1296269]}`,
+		},
+		"single file": {
+			opts: []Option{WithPermissionRequest(PermissionRequest{
+				Permissions: map[string]string{"contents": "read"},
+				SingleFile:  "write",
+			})},
+			want: `{"permissions":{"contents":"read","single_file":"write"}}`,
+		},
+		"single file overrides permissions": {
+			opts: []Option{WithPermissionRequest(PermissionRequest{
+				Permissions: map[string]string{"single_file": "read"},
+				SingleFile:  "write",
+			})},
+			want: `{"permissions":{"single_file":"write"}}`,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Error(err)
+				}
+				got = string(body)
+				w.Header().Set("Content-Type", "application/json")
+				//nolint:errcheck
+				w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+			}))
+			defer ts.Close()
+
+			conf := &Config{
+				JWT: JWT{
+					AppID:      "1",
+					PrivateKey: getPrivateKey(t),
+				},
+				TokenURL: ts.URL,
+			}
+			for _, opt := range tt.opts {
+				opt(conf)
+			}
+			if _, err := conf.Token(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("body = %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_PermissionRequest(t *testing.T) {
+	var c Config
+	want := PermissionRequest{Permissions: map[string]string{"contents": "read"}, SingleFile: "write"}
+	c.SetPermissionRequest(want)
+	if got := c.PermissionRequest(); !reflect.DeepEqual(got, want) {
+		t.Errorf("permission request = %+v; want %+v", got, want)
+	}
+}
+
+func TestJWTFetch_RateLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		w.Header().Set("X-RateLimit-Reset", "2524608000")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	conf := &Config{
+		JWT: JWT{
+			AppID:      "1",
+			PrivateKey: getPrivateKey(t),
+		},
+		TokenURL: ts.URL,
+	}
+	tok, err := conf.TokenSource(context.Background()).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl, ok := RateLimitFromToken(tok)
+	if !ok {
+		t.Fatal("got no rate limit, expected one")
+	}
+	want := RateLimit{Limit: 5000, Remaining: 4999, Reset: time.Unix(2524608000, 0)}
+	if rl != want {
+		t.Errorf("rate limit = %+v; want %+v", rl, want)
+	}
+}
+
 func TestTokenRetrieveError(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-type", "application/json")
@@ -110,9 +279,16 @@ func TestTokenRetrieveError(t *testing.T) {
 	if err == nil {
 		t.Fatalf("got no error, expected one")
 	}
-	_, ok := err.(*oauth2.RetrieveError)
-	if !ok {
-		t.Fatalf("got %T error, expected *RetrieveError", err)
+	var ae *AuthError
+	if !errors.As(err, &ae) {
+		t.Fatalf("got %T error, expected *AuthError", err)
+	}
+	if got, want := ae.StatusCode, http.StatusBadRequest; got != want {
+		t.Errorf("status code = %d; want %d", got, want)
+	}
+	var re *oauth2.RetrieveError
+	if !errors.As(err, &re) {
+		t.Fatalf("got %T error, expected to wrap *RetrieveError", err)
 	}
 	// Test error string for backwards compatibility
 	expected := fmt.Sprintf("oauth2: cannot fetch token: %v\nResponse: %s", "400 Bad Request", `{"error": "invalid_grant"}`)
@@ -121,7 +297,276 @@ func TestTokenRetrieveError(t *testing.T) {
 	}
 }
 
-func getPrivateKey(t *testing.T) *rsa.PrivateKey {
+func TestTokenErrors(t *testing.T) {
+	tests := map[string]struct {
+		body   string
+		closed bool
+		want   error
+	}{
+		"network":        {closed: true, want: ErrTokenFetch},
+		"invalid json":   {body: `{"token": `, want: ErrTokenParse},
+		"invalid expiry": {body: `{"token": "v1.1f699f1069f60xxx", "expires_at": "tomorrow"}`, want: ErrExpiryParse},
+		"too large":      {body: `{"token": "` + strings.Repeat("x", 1<<20) + `"}`, want: ErrResponseTooLarge},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				//nolint:errcheck
+				w.Write([]byte(tt.body))
+			}))
+			if tt.closed {
+				ts.Close()
+			} else {
+				defer ts.Close()
+			}
+
+			conf := &Config{
+				JWT:      JWT{AppID: "1", PrivateKey: getPrivateKey(t)},
+				TokenURL: ts.URL,
+			}
+			_, err := conf.Token(context.Background())
+			for _, sentinel := range []error{ErrTokenFetch, ErrTokenParse, ErrExpiryParse, ErrResponseTooLarge} {
+				if got, want := errors.Is(err, sentinel), sentinel == tt.want; got != want {
+					t.Errorf("errors.Is(%v, %v) = %t; want %t", err, sentinel, got, want)
+				}
+			}
+			if errors.Is(err, ErrAppNotInstalled) {
+				t.Errorf("error = %v; should not match %v", err, ErrAppNotInstalled)
+			}
+			// the error string is kept for backward compatibility
+			if got, want := err.Error(), "oauth2: cannot fetch token: "; !strings.HasPrefix(got, want) {
+				t.Errorf("error = %q; want prefix %q", got, want)
+			}
+		})
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	tests := map[string]struct {
+		raw     string
+		want    time.Time
+		wantErr string
+	}{
+		"seconds":     {raw: "2050-01-01T11:12:13Z", want: time.Date(2050, 1, 1, 11, 12, 13, 0, time.UTC)},
+		"nanoseconds": {raw: "2050-01-01T11:12:13.123456789Z", want: time.Date(2050, 1, 1, 11, 12, 13, 123456789, time.UTC)},
+		"offset":      {raw: "2050-01-01T13:12:13+02:00", want: time.Date(2050, 1, 1, 11, 12, 13, 0, time.UTC)},
+		"invalid":     {raw: "tomorrow", wantErr: `invalid expires_at "tomorrow"`},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseExpiry(tt.raw)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v; want prefix %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("expiry = %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJWTFetch_RequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	conf := &Config{JWT: JWT{AppID: "1", PrivateKey: getPrivateKey(t)}, TokenURL: ts.URL}
+	WithRequestTimeout(50 * time.Millisecond)(conf)
+	_, err := conf.Token(context.Background())
+	if !errors.Is(err, ErrTokenFetch) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v; want a token fetch deadline error", err)
+	}
+}
+
+func TestJWTFetch_HTTPClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	rt := &countingTransport{}
+	conf := &Config{
+		JWT: JWT{
+			AppID:      "1",
+			PrivateKey: getPrivateKey(t),
+		},
+		TokenURL: ts.URL,
+	}
+	WithHTTPClient(&http.Client{Transport: rt})(conf)
+
+	_, err := conf.TokenSource(context.Background()).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rt.count, 1; got != want {
+		t.Errorf("requests = %d; want %d", got, want)
+	}
+}
+
+type countingTransport struct {
+	count int
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.count++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestAuthError_DocumentationURL(t *testing.T) {
+	body := `{"message": "Resource not accessible by integration", "documentation_url": "https://docs.github.com/rest/apps/apps#create-an-installation-access-token-for-an-app"}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		//nolint:errcheck
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	conf := &Config{JWT: JWT{AppID: "1", PrivateKey: getPrivateKey(t)}, TokenURL: ts.URL}
+	_, err := conf.Token(context.Background())
+	var ae *AuthError
+	if !errors.As(err, &ae) {
+		t.Fatalf("error = %v; want an *AuthError", err)
+	}
+	if got, want := ae.Message, "Resource not accessible by integration"; got != want {
+		t.Errorf("message = %q; want %q", got, want)
+	}
+	docs := "https://docs.github.com/rest/apps/apps#create-an-installation-access-token-for-an-app"
+	if ae.DocumentationURL != docs {
+		t.Errorf("documentation URL = %q; want %q", ae.DocumentationURL, docs)
+	}
+	want := "oauth2: cannot fetch token: 403 Forbidden\nResponse: " + body + "\nDocumentation: " + docs
+	if got := err.Error(); got != want {
+		t.Errorf("error = %q; want %q", got, want)
+	}
+}
+
+func TestAuthError_Is(t *testing.T) {
+	tests := map[string]struct {
+		status int
+		body   string
+		want   error
+	}{
+		"not installed": {status: http.StatusNotFound, body: `{"message": "Not Found"}`, want: ErrAppNotInstalled},
+		"suspended":     {status: http.StatusForbidden, body: `{"message": "This installation has been suspended"}`, want: ErrInstallationSuspended},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				//nolint:errcheck
+				w.Write([]byte(tt.body))
+			}))
+			defer ts.Close()
+
+			conf := &Config{
+				JWT: JWT{
+					AppID:      "1",
+					PrivateKey: getPrivateKey(t),
+				},
+				TokenURL: ts.URL,
+			}
+			_, err := conf.TokenSource(context.Background()).Token()
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got %v; want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	key := getPrivateKey(t)
+	shortKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]struct {
+		conf    Config
+		wantErr string
+	}{
+		"valid":        {conf: Config{JWT: JWT{AppID: "1", PrivateKey: key}, TokenURL: "https://api.github.com"}},
+		"empty app ID": {conf: Config{JWT: JWT{PrivateKey: key}, TokenURL: "https://api.github.com"}, wantErr: "jwt: app ID is empty"},
+		"nil key":      {conf: Config{JWT: JWT{AppID: "1"}, TokenURL: "https://api.github.com"}, wantErr: "jwt: private key is nil"},
+		"empty URL":    {conf: Config{JWT: JWT{AppID: "1", PrivateKey: key}}, wantErr: "jwt: token URL is empty"},
+		"short key": {
+			conf:    Config{JWT: JWT{AppID: "1", PrivateKey: shortKey}, TokenURL: "https://api.github.com"},
+			wantErr: "jwt: private key must be at least 2048 bits",
+		},
+		"short fallback key": {
+			conf:    Config{JWT: JWT{AppID: "1", PrivateKey: key, FallbackKeys: []*rsa.PrivateKey{shortKey}}, TokenURL: "https://api.github.com"},
+			wantErr: "jwt: invalid fallback key: jwt: private key must be at least 2048 bits",
+		},
+		"expiry too long": {
+			conf:    Config{JWT: JWT{AppID: "1", PrivateKey: key, Expires: time.Hour}, TokenURL: "https://api.github.com"},
+			wantErr: "jwt: expiry 1h0m0s must be between 0 and 10m0s",
+		},
+		"invalid repository ID": {
+			conf: func() Config {
+				c := Config{JWT: JWT{AppID: "1", PrivateKey: key}, TokenURL: "https://api.github.com"}
+				c.Repositories.IDs = []string{"octo/repo"}
+				return c
+			}(),
+			wantErr: `jwt: invalid repository ID "octo/repo"`,
+		},
+		"too many repositories": {
+			conf: func() Config {
+				c := Config{JWT: JWT{AppID: "1", PrivateKey: key}, TokenURL: "https://api.github.com"}
+				c.Repositories.Names = make([]string, 300)
+				c.Repositories.IDs = make([]string, 201)
+				return c
+			}(),
+			wantErr: "jwt: too many repositories: 501 repositories requested, GitHub accepts at most 500 per token",
+		},
+		"no scheme": {
+			conf:    Config{JWT: JWT{AppID: "1", PrivateKey: key}, TokenURL: "api.github.com/app/installations/1/access_tokens"},
+			wantErr: `jwt: invalid token URL "api.github.com/app/installations/1/access_tokens": scheme must be http or https`,
+		},
+		"invalid scheme": {
+			conf:    Config{JWT: JWT{AppID: "1", PrivateKey: key}, TokenURL: "ftp://api.github.com"},
+			wantErr: `jwt: invalid token URL "ftp://api.github.com": scheme must be http or https`,
+		},
+		"empty host": {
+			conf:    Config{JWT: JWT{AppID: "1", PrivateKey: key}, TokenURL: "https:///app"},
+			wantErr: `jwt: invalid token URL "https:///app": host is empty`,
+		},
+		"unparsable": {
+			conf:    Config{JWT: JWT{AppID: "1", PrivateKey: key}, TokenURL: "https://api.github.com/%zz"},
+			wantErr: `jwt: invalid token URL "https://api.github.com/%zz": parse "https://api.github.com/%zz": invalid URL escape "%zz"`,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tt.conf.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("got error %v, expected none", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("got error %v, expected %q", err, tt.wantErr)
+			}
+			if _, err := tt.conf.Token(context.Background()); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("got token error %v, expected %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func getPrivateKey(t testing.TB) *rsa.PrivateKey {
 	t.Helper()
 	key, err := key.Parse(dummyPrivateKey)
 	if err != nil {
@@ -129,3 +574,78 @@ func getPrivateKey(t *testing.T) *rsa.PrivateKey {
 	}
 	return key
 }
+
+func TestJWTFetch_MissingExpiry(t *testing.T) {
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx"}`))
+	}))
+	defer ts.Close()
+
+	tests := map[string]struct {
+		expires time.Duration
+		want    time.Duration
+	}{
+		"jwt expiry": {expires: 5 * time.Minute, want: 5 * time.Minute},
+		"default":    {want: DefaultFallbackExpiry},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			conf := &Config{
+				JWT:      JWT{AppID: "1", PrivateKey: getPrivateKey(t), Expires: tt.expires},
+				TokenURL: ts.URL,
+			}
+			tok, err := conf.Token(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := now.Add(tt.want); !tok.Expiry.Equal(want) {
+				t.Errorf("expiry = %s; want %s", tok.Expiry, want)
+			}
+		})
+	}
+}
+
+func TestWithResponseHook(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			//nolint:errcheck
+			w.Write([]byte(`{"message": "Server Error"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z", "permissions": {"contents": "read"}}`))
+	}))
+	defer ts.Close()
+
+	var got []string
+	conf := &Config{
+		JWT:      JWT{AppID: "1", PrivateKey: getPrivateKey(t)},
+		TokenURL: ts.URL,
+		Retry:    RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond},
+	}
+	WithResponseHook(func(statusCode int, body []byte) {
+		got = append(got, fmt.Sprintf("%d %s", statusCode, body))
+	})(conf)
+
+	if _, err := conf.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`502 {"message": "Server Error"}`,
+		`201 {"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z", "permissions": {"contents": "read"}}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("responses = %q; want %q", got, want)
+	}
+}
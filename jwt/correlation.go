@@ -0,0 +1,27 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+import "context"
+
+// CorrelationIDHeader is the header of the token requests carrying the correlation ID of the context.
+const CorrelationIDHeader = "X-Correlation-ID"
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a copy of the context carrying the correlation ID of the caller's request,
+// e.g. a trace or request ID, so that the token fetches can be correlated with it without OpenTelemetry.
+// The token requests sent with the context have the CorrelationIDHeader header,
+// and their logs and spans the correlation_id key.
+// Concurrent token fetches of an installation are shared: they use the correlation ID of the first caller.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID returns the correlation ID of the context, or an empty string if there is none.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCorrelationID(t *testing.T) {
+	var header string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(CorrelationIDHeader)
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	conf := &Config{JWT: JWT{AppID: "1", PrivateKey: getPrivateKey(t)}, TokenURL: ts.URL}
+	WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))(conf)
+
+	if _, err := conf.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if header != "" {
+		t.Errorf("correlation ID header = %q; want none", header)
+	}
+	if logs := buf.String(); strings.Contains(logs, "correlation_id") {
+		t.Errorf("logs contain a correlation ID:\n%s", logs)
+	}
+
+	ctx := WithCorrelationID(context.Background(), "trace-123")
+	if got, want := CorrelationID(ctx), "trace-123"; got != want {
+		t.Errorf("correlation ID = %q; want %q", got, want)
+	}
+	if _, err := conf.Token(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := header, "trace-123"; got != want {
+		t.Errorf("correlation ID header = %q; want %q", got, want)
+	}
+	if logs := buf.String(); !strings.Contains(logs, "correlation_id=trace-123") {
+		t.Errorf("logs do not contain the correlation ID:\n%s", logs)
+	}
+}
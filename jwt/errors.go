@@ -0,0 +1,159 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrAppNotInstalled is matched by an AuthError when the App installation does not exist.
+	ErrAppNotInstalled = errors.New("github app is not installed")
+
+	// ErrInstallationSuspended is matched by an AuthError when the App installation is suspended.
+	ErrInstallationSuspended = errors.New("github app installation is suspended")
+
+	// ErrTokenFetch is matched when a token request could not be sent or its response could not be read,
+	// e.g. on network failures. The underlying error is also wrapped.
+	ErrTokenFetch = errors.New("jwt: token request failed")
+
+	// ErrTokenParse is matched when a token response body is not valid JSON.
+	ErrTokenParse = errors.New("jwt: invalid token response")
+
+	// ErrExpiryParse is matched when the expiry of a token response is not a valid timestamp.
+	ErrExpiryParse = errors.New("jwt: invalid token expiry")
+
+	// ErrResponseTooLarge is matched when a token response is larger than the maximum size read.
+	ErrResponseTooLarge = errors.New("jwt: response too large")
+
+	// ErrTooManyRepositories is returned when a token request has more than MaxRepositories repositories.
+	ErrTooManyRepositories = errors.New("jwt: too many repositories")
+)
+
+// tokenError is returned when a token could not be fetched.
+// It wraps both the matching sentinel error and the underlying error,
+// keeping the error string of the oauth2 package.
+type tokenError struct {
+	kind error
+	err  error
+}
+
+func (e *tokenError) Error() string {
+	return "oauth2: cannot fetch token: " + e.err.Error()
+}
+
+func (e *tokenError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// AuthError is returned when GitHub rejects a token request.
+// It wraps the underlying *oauth2.RetrieveError and can be matched
+// against ErrAppNotInstalled and ErrInstallationSuspended using errors.Is.
+type AuthError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Message is the error message returned by GitHub.
+	Message string `json:"message"`
+
+	// DocumentationURL is the link to the relevant GitHub API documentation.
+	DocumentationURL string `json:"documentation_url"`
+
+	// RequestID is the X-GitHub-Request-Id header of the response,
+	// which GitHub support asks for when diagnosing problems.
+	RequestID string `json:"-"`
+
+	Err *oauth2.RetrieveError `json:"-"`
+}
+
+func newAuthError(resp *http.Response, body []byte) *AuthError {
+	e := &AuthError{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-GitHub-Request-Id"),
+		Err:        &oauth2.RetrieveError{Response: resp, Body: body},
+	}
+	//nolint:errcheck
+	json.Unmarshal(body, e) // the error body is optional
+	return e
+}
+
+func (e *AuthError) Error() string {
+	if e.DocumentationURL == "" {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + "\nDocumentation: " + e.DocumentationURL
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error matches one of the package sentinel errors.
+func (e *AuthError) Is(target error) bool {
+	switch target {
+	case ErrAppNotInstalled:
+		return e.StatusCode == http.StatusNotFound
+	case ErrInstallationSuspended:
+		return e.StatusCode == http.StatusForbidden && strings.Contains(strings.ToLower(e.Message), "suspended")
+	}
+	return false
+}
+
+// requestID returns the GitHub request ID of the error, if any.
+func requestID(err error) string {
+	var ae *AuthError
+	if errors.As(err, &ae) {
+		return ae.RequestID
+	}
+	return ""
+}
+
+// RateLimitError is returned when a token request is rate limited by GitHub.
+// It wraps the underlying *oauth2.RetrieveError.
+type RateLimitError struct {
+	// Reset is the time after which the request can be retried.
+	// It is zero if GitHub did not indicate it.
+	Reset time.Time
+
+	Err error
+}
+
+func (e *RateLimitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// rateLimited reports whether the response indicates a rate limited request.
+func rateLimited(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		return resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0"
+	}
+	return false
+}
+
+// rateLimitReset returns the time after which a rate limited request can be retried,
+// using either the Retry-After (seconds) or the X-RateLimit-Reset (epoch seconds) header.
+func rateLimitReset(resp *http.Response, now time.Time) time.Time {
+	if s, err := strconv.ParseInt(resp.Header.Get("Retry-After"), 10, 64); err == nil && s >= 0 {
+		return now.Add(time.Duration(s) * time.Second)
+	}
+	if s, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil && s > 0 {
+		return time.Unix(s, 0)
+	}
+	return time.Time{}
+}
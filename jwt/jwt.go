@@ -8,17 +8,31 @@
 package jwt
 
 import (
+	"context"
 	"crypto/rsa"
+	"errors"
+	"fmt"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/beatlabs/github-auth/jws"
+	"golang.org/x/oauth2"
 )
 
 var (
 	defaultHeader = &jws.Header{Algorithm: "RS256", Typ: "JWT"}
 )
 
+const (
+	// DefaultAPIVersion is the GitHub REST API version requested when none is configured.
+	// See: https://docs.github.com/en/rest/about-the-rest-api/api-versions
+	DefaultAPIVersion = "2022-11-28"
+
+	// mediaType is the GitHub REST API media type.
+	mediaType = "application/vnd.github+json"
+)
+
 // JWT is the base structure for GitHub JWT.
 type JWT struct {
 	// AppID is the GitHub app ID.
@@ -34,24 +48,249 @@ type JWT struct {
 	//
 	PrivateKey *rsa.PrivateKey
 
+	// SharedKey optionally holds the private key instead of PrivateKey, so that the JWTs sharing it
+	// switch to a new key once it is replaced, e.g. the Installation configs derived from an App config.
+	SharedKey *atomic.Pointer[rsa.PrivateKey]
+
+	// Signer optionally signs the JWTs instead of PrivateKey, which can then be nil,
+	// e.g. when the private key is held by a KMS. It must produce RS256 signatures.
+	Signer jws.Signer
+
+	// PublicKey optionally specifies the public key of the signing key,
+	// e.g. to publish a JWKS document when the private key is not available.
+	// If both are set, it must match PrivateKey.
+	PublicKey *rsa.PublicKey
+
+	// FallbackKeys optionally specifies the keys used, in order, when GitHub rejects
+	// the JWTs signed with PrivateKey while fetching tokens, e.g. during a key rotation.
+	FallbackKeys []*rsa.PrivateKey
+
 	// Expires optionally specifies how long the token is valid for.
 	Expires time.Duration
+
+	// IssuedAtSkew optionally specifies how far in the past the JWTs are issued,
+	// for clocks which are not in sync with GitHub. If zero, the JWTs are issued now,
+	// unlike jws.Encode which issues them 10 seconds in the past.
+	// The App and Installation configs use DefaultIssuedAtSkew.
+	IssuedAtSkew time.Duration
+
+	// KeyID optionally specifies the kid header of the signed JWTs,
+	// so that verifiers can select the key, e.g. key.Thumbprint of the public key.
+	// If empty, the header is omitted.
+	KeyID string
+
+	// APIVersion optionally specifies the X-GitHub-Api-Version header of the requests.
+	// If empty, DefaultAPIVersion is used.
+	APIVersion string
+
+	// UserAgent optionally specifies the User-Agent header of the requests.
+	// If empty, DefaultUserAgent is used.
+	UserAgent string
+
+	// Header optionally specifies extra headers added to every request, e.g. for a proxy.
+	// They are not added when the request already sets them, they take precedence over
+	// the default Accept, X-GitHub-Api-Version and User-Agent headers,
+	// and they never replace the Authorization header.
+	Header http.Header
+
+	// Transport optionally specifies the base HTTP transport used by Client.
+	// If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// Clock optionally returns the current time used for all time decisions:
+	// the JWT claims, the token cache expiry and the retry deadlines.
+	// It is primarily meant for tests. If nil, the real clock is used.
+	Clock func() time.Time
+}
+
+const (
+	// MaxExpires is the maximum lifetime of the JWTs accepted by GitHub.
+	MaxExpires = 10 * time.Minute
+
+	// DefaultIssuedAtSkew is the issued at skew recommended by GitHub.
+	// See: https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/generating-a-json-web-token-jwt-for-a-github-app
+	DefaultIssuedAtSkew = 60 * time.Second
+
+	// minKeySize is the minimum size in bits of the RSA keys generated by GitHub.
+	minKeySize = 2048
+)
+
+// Validate checks that the fields required to sign a JWT are set and valid,
+// without any network call.
+func (j *JWT) Validate() error {
+	if j.AppID == "" {
+		return errors.New("jwt: app ID is empty")
+	}
+	switch key := j.Key(); {
+	case key != nil:
+		if err := validateKey(key); err != nil {
+			return err
+		}
+		if j.PublicKey != nil && !key.PublicKey.Equal(j.PublicKey) {
+			return errors.New("jwt: public key does not match the private key")
+		}
+	case j.Signer == nil:
+		return errors.New("jwt: private key is nil")
+	case j.PublicKey != nil && (j.PublicKey.N == nil || j.PublicKey.N.BitLen() < minKeySize):
+		return fmt.Errorf("jwt: public key must be at least %d bits", minKeySize)
+	}
+	for _, key := range j.FallbackKeys {
+		if err := validateKey(key); err != nil {
+			return fmt.Errorf("jwt: invalid fallback key: %w", err)
+		}
+	}
+	if j.Expires < 0 || j.Expires > MaxExpires {
+		return fmt.Errorf("jwt: expiry %s must be between 0 and %s", j.Expires, MaxExpires)
+	}
+	if j.IssuedAtSkew < 0 {
+		return fmt.Errorf("jwt: issued at skew %s must not be negative", j.IssuedAtSkew)
+	}
+	return nil
+}
+
+// validateKey checks that the key is usable to sign GitHub JWTs.
+func validateKey(key *rsa.PrivateKey) error {
+	if key == nil {
+		return errors.New("jwt: private key is nil")
+	}
+	if key.N == nil || key.N.BitLen() < minKeySize {
+		return fmt.Errorf("jwt: private key must be at least %d bits", minKeySize)
+	}
+	return nil
+}
+
+// Key returns the private key signing the JWTs: the key held by SharedKey, if set, or else PrivateKey.
+func (j *JWT) Key() *rsa.PrivateKey {
+	if j.SharedKey != nil {
+		return j.SharedKey.Load()
+	}
+	return j.PrivateKey
+}
+
+// SetKey replaces the private key signing the JWTs, held by SharedKey if set, or else PrivateKey,
+// and forgets the payloads signed with the previous key, so that they are no longer used
+// and the previous key is not kept in memory. Only the replacement of a SharedKey is safe
+// to call concurrently with the requests.
+func (j *JWT) SetKey(key *rsa.PrivateKey) {
+	var old *rsa.PrivateKey
+	if j.SharedKey != nil {
+		old = j.SharedKey.Swap(key)
+	} else {
+		old, j.PrivateKey = j.PrivateKey, key
+	}
+	if old != nil && old != key {
+		forgetKey(old)
+	}
+}
+
+// Public returns the public key of the signing key: PublicKey,
+// or else the public key of the private key, or nil if neither is set.
+func (j *JWT) Public() *rsa.PublicKey {
+	if j.PublicKey != nil {
+		return j.PublicKey
+	}
+	if key := j.Key(); key != nil {
+		return &key.PublicKey
+	}
+	return nil
+}
+
+// addHeaders adds the extra headers which are not set in h, except Authorization.
+func (j *JWT) addHeaders(h http.Header) {
+	for k, vv := range j.Header {
+		k = http.CanonicalHeaderKey(k)
+		if k == "Authorization" || len(h.Values(k)) > 0 {
+			continue
+		}
+		h[k] = append([]string(nil), vv...)
+	}
+}
+
+// apiVersion returns the X-GitHub-Api-Version header of the requests.
+func (j *JWT) apiVersion() string {
+	if j.APIVersion != "" {
+		return j.APIVersion
+	}
+	return DefaultAPIVersion
 }
 
 // Payload returns the encoded GitHub JWT payload.
 //
 func (j *JWT) Payload() (string, error) {
-	claimSet := &jws.ClaimSet{
-		Iss: j.AppID,
+	if err := j.Validate(); err != nil {
+		return "", err
 	}
+	return j.sign(j.Key())
+}
+
+// Claims returns the claim set of the JWT payload which would be signed now,
+// without signing it, e.g. to display the issuer and the validity period.
+func (j *JWT) Claims() (jws.ClaimSet, error) {
+	if err := j.Validate(); err != nil {
+		return jws.ClaimSet{}, err
+	}
+	return j.claims(j.now()), nil
+}
+
+// now returns the current time of the Clock, or of the real clock if it is not set.
+func (j *JWT) now() time.Time {
+	if j.Clock != nil {
+		return j.Clock()
+	}
+	return timeNow()
+}
+
+// claims returns the claim set of a JWT signed at now.
+// Without expiry, the JWT expires one hour after it is issued, as with jws.Encode.
+func (j *JWT) claims(now time.Time) jws.ClaimSet {
+	iat := now.Add(-j.IssuedAtSkew)
+	c := jws.ClaimSet{Iss: j.AppID, Iat: iat.Unix(), Exp: iat.Add(time.Hour).Unix()}
 	if t := j.Expires; t > 0 {
-		claimSet.Exp = time.Now().Add(t).Unix()
+		c.Exp = now.Add(t).Unix()
+	}
+	return c
+}
+
+// payloadKey returns the cache key of the payloads signed with the provided key.
+func (j *JWT) payloadKey(key *rsa.PrivateKey) payloadKey {
+	return payloadKey{appID: j.AppID, keyID: j.KeyID, expires: j.Expires, skew: j.IssuedAtSkew, key: key}
+}
+
+// forgetPayloads removes the cached payloads signed with the private key and the fallback keys.
+func (j *JWT) forgetPayloads() {
+	forgetPayload(j.payloadKey(j.Key()))
+	for _, key := range j.FallbackKeys {
+		forgetPayload(j.payloadKey(key))
+	}
+}
+
+// sign returns the GitHub JWT payload signed with the provided key, or with the Signer if the key is nil.
+// Payloads signed with a key and with an expiry are reused until they are about to expire.
+func (j *JWT) sign(key *rsa.PrivateKey) (string, error) {
+	now := j.now()
+	k := j.payloadKey(key)
+	cache := j.Expires > 0 && key != nil
+	if cache {
+		if payload, ok := cachedPayload(k, now); ok {
+			return payload, nil
+		}
 	}
+	claimSet := j.claims(now)
 	h := *defaultHeader
-	payload, err := jws.Encode(&h, claimSet, j.PrivateKey)
+	h.KeyID = j.KeyID
+	var payload string
+	var err error
+	if key != nil {
+		payload, err = jws.Encode(&h, &claimSet, key)
+	} else {
+		payload, err = jws.EncodeWithSigner(&h, &claimSet, j.Signer)
+	}
 	if err != nil {
 		return "", err
 	}
+	if cache {
+		cachePayload(k, payload, time.Unix(claimSet.Exp, 0), now)
+	}
 
 	return payload, nil
 }
@@ -61,22 +300,60 @@ func (j *JWT) Payload() (string, error) {
 //
 func (j *JWT) Client() *http.Client {
 	return &http.Client{
-		Transport: &transport{j},
+		Transport: &transport{jwt: j, base: j.Transport},
+	}
+}
+
+// ClientWithContext returns an HTTP client adding Authorization headers,
+// wrapping the Transport or else the transport of the context's HTTP client,
+// as set with the oauth2.HTTPClient context key.
+// Once the context is done, the requests of the client fail with its error.
+func (j *JWT) ClientWithContext(ctx context.Context) *http.Client {
+	base := j.Transport
+	if hc, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok && hc != nil && base == nil {
+		base = hc.Transport
+	}
+	return &http.Client{
+		Transport: &transport{jwt: j, base: base, ctx: ctx},
 	}
 }
 
 // Custom transport for adding required HTTP headers.
 //
 type transport struct {
-	jwt *JWT
+	jwt  *JWT
+	base http.RoundTripper
+	ctx  context.Context
+}
+
+func (t *transport) baseTransport() http.RoundTripper {
+	if t.base != nil {
+		return t.base
+	}
+	return http.DefaultTransport
 }
 
 func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
-	r.Header.Add("Accept", "application/vnd.github.v3+json")
+	if t.ctx != nil && t.ctx.Err() != nil {
+		if r.Body != nil {
+			r.Body.Close()
+		}
+		return nil, t.ctx.Err()
+	}
+	t.jwt.addHeaders(r.Header)
+	if r.Header.Get("Accept") == "" {
+		r.Header.Set("Accept", mediaType)
+	}
+	if r.Header.Get("X-GitHub-Api-Version") == "" {
+		r.Header.Set("X-GitHub-Api-Version", t.jwt.apiVersion())
+	}
+	if r.Header.Get("User-Agent") == "" {
+		r.Header.Set("User-Agent", t.jwt.userAgent())
+	}
 	payload, err := t.jwt.Payload()
 	if err != nil {
 		return nil, err
 	}
-	r.Header.Add("Authorization", "Bearer "+payload)
-	return http.DefaultTransport.RoundTrip(r)
+	r.Header.Set("Authorization", "Bearer "+payload)
+	return t.baseTransport().RoundTrip(r)
 }
@@ -0,0 +1,372 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beatlabs/github-auth/jws"
+	"golang.org/x/oauth2"
+)
+
+func TestClient_Transport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	rt := &recordingTransport{}
+	j := &JWT{
+		AppID:      "1",
+		PrivateKey: getPrivateKey(t),
+		Transport:  rt,
+	}
+
+	resp, err := j.Client().Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if got, want := len(rt.requests), 1; got != want {
+		t.Fatalf("requests = %d; want %d", got, want)
+	}
+	auth := rt.requests[0].Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		t.Errorf("Authorization = %q; want Bearer prefix", auth)
+	}
+}
+
+func TestClientWithContext(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	rt := &recordingTransport{}
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: rt})
+	j := &JWT{AppID: "1", PrivateKey: getPrivateKey(t)}
+	client := j.ClientWithContext(ctx)
+
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got, want := len(rt.requests), 1; got != want {
+		t.Fatalf("requests = %d; want %d, the context transport is used", got, want)
+	}
+
+	cancel()
+	if _, err := client.Get(ts.URL); !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v; want %v", err, context.Canceled)
+	}
+	if got, want := len(rt.requests), 1; got != want {
+		t.Errorf("requests = %d; want %d after the context is done", got, want)
+	}
+}
+
+type recordingTransport struct {
+	requests []*http.Request
+}
+
+func (t *recordingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.requests = append(t.requests, r)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestJWT_Payload_KeyID(t *testing.T) {
+	tests := map[string]struct {
+		kid  string
+		want string
+	}{
+		"empty": {want: `{"alg":"RS256","typ":"JWT"}`},
+		"set":   {kid: "key-1", want: `{"alg":"RS256","typ":"JWT","kid":"key-1"}`},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			j := &JWT{AppID: "1", PrivateKey: getPrivateKey(t), KeyID: tt.kid}
+			payload, err := j.Payload()
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, err := base64.RawURLEncoding.DecodeString(strings.Split(payload, ".")[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(header); got != tt.want {
+				t.Errorf("header = %s; want %s", got, tt.want)
+			}
+			var h struct {
+				KeyID string `json:"kid"`
+			}
+			if err := json.Unmarshal(header, &h); err != nil {
+				t.Fatal(err)
+			}
+			if h.KeyID != tt.kid {
+				t.Errorf("kid = %q; want %q", h.KeyID, tt.kid)
+			}
+		})
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	tests := map[string]struct {
+		ua   string
+		want string
+	}{
+		"default": {want: DefaultUserAgent},
+		"custom":  {ua: "octoapp/1.0", want: "octoapp/1.0"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = append(got, r.Header.Get("User-Agent"))
+				w.Header().Set("Content-Type", "application/json")
+				//nolint:errcheck
+				w.Write([]byte(`{"token": "v1.1f699f1069f60xxx"}`))
+			}))
+			defer ts.Close()
+
+			conf := &Config{JWT: JWT{AppID: "1", PrivateKey: getPrivateKey(t)}, TokenURL: ts.URL}
+			WithUserAgent(tt.ua)(conf)
+			if _, err := conf.Token(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			resp, err := conf.JWT.Client().Get(ts.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if len(got) != 2 || got[0] != tt.want || got[1] != tt.want {
+				t.Errorf("user agents = %q; want %q for both requests", got, tt.want)
+			}
+		})
+	}
+	if !strings.HasPrefix(DefaultUserAgent, "github-auth/") {
+		t.Errorf("default user agent = %q; want github-auth/ prefix", DefaultUserAgent)
+	}
+}
+
+func TestAPIVersion(t *testing.T) {
+	tests := map[string]struct {
+		version string
+		want    string
+	}{
+		"default": {want: DefaultAPIVersion},
+		"custom":  {version: "2026-03-10", want: "2026-03-10"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if accept := r.Header.Get("Accept"); accept != "application/vnd.github+json" {
+					t.Errorf("Accept = %q; want application/vnd.github+json", accept)
+				}
+				got = append(got, r.Header.Get("X-GitHub-Api-Version"))
+				w.Header().Set("Content-Type", "application/json")
+				//nolint:errcheck
+				w.Write([]byte(`{"token": "v1.1f699f1069f60xxx"}`))
+			}))
+			defer ts.Close()
+
+			conf := &Config{JWT: JWT{AppID: "1", PrivateKey: getPrivateKey(t)}, TokenURL: ts.URL}
+			WithAPIVersion(tt.version)(conf)
+			if _, err := conf.Token(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			resp, err := conf.JWT.Client().Get(ts.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if len(got) != 2 || got[0] != tt.want || got[1] != tt.want {
+				t.Errorf("API versions = %q; want %q for both requests", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeader(t *testing.T) {
+	var got []http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Clone())
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx"}`))
+	}))
+	defer ts.Close()
+
+	conf := &Config{JWT: JWT{AppID: "1", PrivateKey: getPrivateKey(t)}, TokenURL: ts.URL}
+	WithHeader(http.Header{
+		"x-proxy-auth":  {"secret"},
+		"User-Agent":    {"octoapp/1.0"},
+		"Authorization": {"Basic proxy"},
+	})(conf)
+	if _, err := conf.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Proxy-Auth", "request")
+	resp, err := conf.JWT.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if len(got) != 2 {
+		t.Fatalf("requests = %d; want 2", len(got))
+	}
+	for i, want := range []string{"secret", "request"} {
+		if v := got[i].Get("X-Proxy-Auth"); v != want {
+			t.Errorf("request %d: X-Proxy-Auth = %q; want %q", i, v, want)
+		}
+		if v := got[i].Get("User-Agent"); v != "octoapp/1.0" {
+			t.Errorf("request %d: User-Agent = %q; want octoapp/1.0", i, v)
+		}
+		if v := got[i].Values("Authorization"); len(v) != 1 || !strings.HasPrefix(v[0], "Bearer ") {
+			t.Errorf("request %d: Authorization = %q; want a single Bearer token", i, v)
+		}
+	}
+}
+
+func TestJWT_Claims(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	j := &JWT{AppID: "1", PrivateKey: getPrivateKey(t), Expires: 5 * time.Minute, IssuedAtSkew: 10 * time.Second}
+	claims, err := j.Claims()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Iss != "1" || claims.Iat != now.Unix()-10 || claims.Exp != now.Add(5*time.Minute).Unix() {
+		t.Errorf("claims = %+v; want iss 1, iat %d and exp %d", claims, now.Unix()-10, now.Add(5*time.Minute).Unix())
+	}
+
+	payload, err := j.sign(j.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(strings.Split(payload, ".")[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(want) {
+		t.Errorf("signed claims = %s; want %s", b, want)
+	}
+
+	if _, err := (&JWT{AppID: "1"}).Claims(); err == nil {
+		t.Error("expected an error without private key")
+	}
+}
+
+func TestJWT_Signer(t *testing.T) {
+	key := getPrivateKey(t)
+	signs := 0
+	signer := func(data []byte) ([]byte, error) {
+		signs++
+		sum := sha256.Sum256(data)
+		return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	}
+	conf := &Config{JWT: JWT{AppID: "1", Expires: time.Minute}}
+	WithSigner(signer, &key.PublicKey)(conf)
+
+	payload, err := conf.Payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := jws.Verify(payload, conf.Public()); err != nil {
+		t.Errorf("the payload is not signed by the signer: %v", err)
+	}
+	if _, err := conf.Payload(); err != nil {
+		t.Fatal(err)
+	}
+	if signs != 2 {
+		t.Errorf("signs = %d; want 2, the payloads of signers are not cached", signs)
+	}
+	if got := conf.Public(); !got.Equal(&key.PublicKey) {
+		t.Error("Public does not return the public key")
+	}
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.PrivateKey = key
+	WithPublicKey(&other.PublicKey)(conf)
+	if err := conf.Validate(); err == nil || err.Error() != "jwt: public key does not match the private key" {
+		t.Errorf("error = %v; want a public key mismatch", err)
+	}
+}
+
+func TestJWT_IssuedAtSkew(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	tests := map[string]struct {
+		skew time.Duration
+		want int64
+	}{
+		"default": {skew: DefaultIssuedAtSkew, want: now.Unix() - 60},
+		"zero":    {want: now.Unix()},
+		"custom":  {skew: 5 * time.Second, want: now.Unix() - 5},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			conf := &Config{JWT: JWT{AppID: "1", PrivateKey: getPrivateKey(t), Expires: 10 * time.Minute}}
+			WithIssuedAtSkew(tt.skew)(conf)
+			claims, err := conf.Claims()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.Iat != tt.want {
+				t.Errorf("iat = %d; want %d", claims.Iat, tt.want)
+			}
+			if want := now.Add(10 * time.Minute).Unix(); claims.Exp != want {
+				t.Errorf("exp = %d; want %d, the expiry is not shifted", claims.Exp, want)
+			}
+		})
+	}
+
+	j := &JWT{AppID: "1", PrivateKey: getPrivateKey(t), IssuedAtSkew: -time.Second}
+	if err := j.Validate(); err == nil {
+		t.Error("expected an error for the negative skew")
+	}
+}
+
+func TestJWT_Clock(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	j := &JWT{AppID: "1", PrivateKey: getPrivateKey(t), Expires: 5 * time.Minute, Clock: func() time.Time { return now }}
+	claims, err := j.Claims()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Iat != now.Unix() || claims.Exp != now.Add(5*time.Minute).Unix() {
+		t.Errorf("claims = %+v; want iat %d and exp %d", claims, now.Unix(), now.Add(5*time.Minute).Unix())
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+// Logger is a structured logger.
+// The keysAndValues are alternating keys and values, e.g. "status", 401.
+// It is satisfied by *slog.Logger.
+//
+// Tokens and private keys are never logged.
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+// nopLogger is the default Logger, discarding all logs.
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+
+// Log returns the configured Logger, or a no-op Logger if none is configured.
+// The logs include the auth_level key: AuthLevelInstallation with the installation_id key
+// for the installation configurations, AuthLevelApp with the app_id key otherwise.
+func (c *Config) Log() Logger {
+	if c.Logger == nil {
+		return nopLogger{}
+	}
+	if c.InstallationID != "" {
+		return valuesLogger{l: c.Logger, kv: []interface{}{"auth_level", AuthLevelInstallation, "installation_id", c.InstallationID}}
+	}
+	return valuesLogger{l: c.Logger, kv: []interface{}{"auth_level", AuthLevelApp, "app_id", c.AppID}}
+}
+
+// valuesLogger is a Logger prepending keys and values to the logs.
+type valuesLogger struct {
+	l  Logger
+	kv []interface{}
+}
+
+func (v valuesLogger) Debug(msg string, keysAndValues ...interface{}) {
+	v.l.Debug(msg, v.with(keysAndValues)...)
+}
+
+func (v valuesLogger) Info(msg string, keysAndValues ...interface{}) {
+	v.l.Info(msg, v.with(keysAndValues)...)
+}
+
+func (v valuesLogger) Error(msg string, keysAndValues ...interface{}) {
+	v.l.Error(msg, v.with(keysAndValues)...)
+}
+
+func (v valuesLogger) with(keysAndValues []interface{}) []interface{} {
+	return append(append(make([]interface{}, 0, len(v.kv)+len(keysAndValues)), v.kv...), keysAndValues...)
+}
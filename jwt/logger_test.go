@@ -0,0 +1,110 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	conf := &Config{
+		JWT: JWT{
+			AppID:      "1",
+			PrivateKey: getPrivateKey(t),
+		},
+		TokenURL: ts.URL,
+		Retry:    RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond},
+	}
+	WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))(conf)
+
+	_, err := conf.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := buf.String()
+	for _, want := range []string{"fetching token", "retrying token request", "status=502", "fetched token"} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("logs do not contain %q:\n%s", want, logs)
+		}
+	}
+	if strings.Contains(logs, "v1.1f699f1069f60xxx") {
+		t.Errorf("logs contain the token:\n%s", logs)
+	}
+}
+
+func TestAuthError_RequestID(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-GitHub-Request-Id", "CAFE:1234:5678")
+		w.WriteHeader(http.StatusUnauthorized)
+		//nolint:errcheck
+		w.Write([]byte(`{"message": "Bad credentials"}`))
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	conf := &Config{JWT: JWT{AppID: "1", PrivateKey: getPrivateKey(t)}, TokenURL: ts.URL}
+	WithLogger(slog.New(slog.NewTextHandler(&buf, nil)))(conf)
+
+	_, err := conf.Token(context.Background())
+	var ae *AuthError
+	if !errors.As(err, &ae) {
+		t.Fatalf("error = %v; want an *AuthError", err)
+	}
+	if got, want := ae.RequestID, "CAFE:1234:5678"; got != want {
+		t.Errorf("request ID = %q; want %q", got, want)
+	}
+	if logs := buf.String(); !strings.Contains(logs, "request_id=CAFE:1234:5678") {
+		t.Errorf("logs do not contain the request ID:\n%s", logs)
+	}
+}
+
+func TestConfig_Log_AuthLevel(t *testing.T) {
+	tests := map[string]struct {
+		conf Config
+		want string
+	}{
+		"app":          {conf: Config{JWT: JWT{AppID: "1"}}, want: "msg=hello auth_level=app app_id=1 key=value"},
+		"installation": {conf: Config{JWT: JWT{AppID: "1"}, InstallationID: "2"}, want: "msg=hello auth_level=installation installation_id=2 key=value"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+				ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey || a.Key == slog.LevelKey {
+						return slog.Attr{}
+					}
+					return a
+				},
+			})))(&tt.conf)
+			tt.conf.Log().Info("hello", "key", "value")
+			if got := strings.TrimSpace(buf.String()); got != tt.want {
+				t.Errorf("log = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"time"
+)
+
+// The results of the token operations.
+const (
+	// ResultSuccess is the result of a successful token fetch.
+	ResultSuccess = "success"
+
+	// ResultError is the result of a failed token fetch.
+	ResultError = "error"
+
+	// ResultCacheHit is the result of a token served from the cache or the token store.
+	ResultCacheHit = "cache_hit"
+)
+
+// The authentication levels of the API requests.
+const (
+	// AuthLevelApp is the level of the requests authenticated as the App with a JWT, e.g. VerifyApp.
+	AuthLevelApp = "app"
+
+	// AuthLevelInstallation is the level of the token fetches of an installation
+	// and of the requests authenticated with its tokens.
+	AuthLevelInstallation = "installation"
+)
+
+// Metrics records the token operations.
+// The github.com/beatlabs/github-auth/prommetrics module provides a Prometheus implementation.
+type Metrics interface {
+	// ObserveToken records a token operation of the installation with its result,
+	// the HTTP status code (0 if there was no response) and its duration (0 for cache hits).
+	ObserveToken(installationID, result string, statusCode int, duration time.Duration)
+}
+
+// RequestMetrics is optionally implemented by Metrics to record the API requests sent by the clients,
+// e.g. to tell the App requests apart from the installation requests in dashboards.
+type RequestMetrics interface {
+	// ObserveRequest records an API request with its authentication level (AuthLevelApp or AuthLevelInstallation),
+	// the App or installation ID, the HTTP status code (0 if there was no response) and its duration.
+	ObserveRequest(authLevel, id string, statusCode int, duration time.Duration)
+}
+
+// nopMetrics is the default Metrics, recording nothing.
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveToken(string, string, int, time.Duration) {}
+
+// Recorder returns the configured Metrics, or a no-op Metrics if none is configured.
+func (c *Config) Recorder() Metrics {
+	if c.Metrics == nil {
+		return nopMetrics{}
+	}
+	return c.Metrics
+}
+
+// ObserveRequest records an API request with the configured Metrics, if they implement RequestMetrics,
+// labelled with the authentication level and the installation or App ID.
+func (c *Config) ObserveRequest(statusCode int, duration time.Duration) {
+	m, ok := c.Metrics.(RequestMetrics)
+	if !ok {
+		return
+	}
+	if c.InstallationID != "" {
+		m.ObserveRequest(AuthLevelInstallation, c.InstallationID, statusCode, duration)
+		return
+	}
+	m.ObserveRequest(AuthLevelApp, c.AppID, statusCode, duration)
+}
@@ -0,0 +1,216 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"crypto/rsa"
+	"net/http"
+	"time"
+
+	"github.com/beatlabs/github-auth/endpoint"
+	"github.com/beatlabs/github-auth/jws"
+)
+
+// Option configures a Config.
+type Option func(*Config)
+
+// WithHTTPClient sets the HTTP client used to fetch tokens.
+// By default the client from the context is used.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Config) {
+		c.HTTPClient = client
+	}
+}
+
+// WithEndpoint sets the GitHub API endpoint the App and Installation configs derive their URLs from,
+// e.g. endpoint.NewFromEnv or a test server, replacing the endpoint of their constructor.
+// A nil endpoint is ignored.
+func WithEndpoint(e *endpoint.Endpoint) Option {
+	return func(c *Config) {
+		if e != nil {
+			c.Endpoint = e
+		}
+	}
+}
+
+// WithRawPath keeps the enterprise URL of the App and Installation constructors as provided,
+// e.g. a proxy serving the GitHub API at its root, instead of appending endpoint.EnterprisePath
+// to bare host URLs. See endpoint.WithRawPath.
+func WithRawPath() Option {
+	return func(c *Config) {
+		if c.Endpoint != nil {
+			c.Endpoint = c.Endpoint.Raw()
+		}
+	}
+}
+
+// WithTransport sets the base HTTP transport used for App (JWT) requests.
+// By default http.DefaultTransport is used.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Config) {
+		c.Transport = rt
+	}
+}
+
+// WithRequestTimeout limits the duration of each token request, as a context deadline
+// which only applies when the caller's context has no shorter deadline.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.RequestTimeout = d
+	}
+}
+
+// WithUserAgent sets the User-Agent header of the requests,
+// e.g. the name of the App. By default DefaultUserAgent is used.
+func WithUserAgent(ua string) Option {
+	return func(c *Config) {
+		c.UserAgent = ua
+	}
+}
+
+// WithAPIVersion sets the GitHub REST API version of the requests, e.g. 2022-11-28.
+// By default DefaultAPIVersion is used.
+func WithAPIVersion(version string) Option {
+	return func(c *Config) {
+		c.APIVersion = version
+	}
+}
+
+// WithHeader sets extra headers added to every request, e.g. the headers required by a proxy.
+// See JWT.Header for their precedence.
+func WithHeader(h http.Header) Option {
+	return func(c *Config) {
+		c.Header = h.Clone()
+	}
+}
+
+// WithSigner signs the JWTs with the provided signer instead of the private key, which can be nil,
+// e.g. with a KMS. The public key of the signing key is optional.
+func WithSigner(signer jws.Signer, pub *rsa.PublicKey) Option {
+	return func(c *Config) {
+		c.Signer = signer
+		c.PublicKey = pub
+	}
+}
+
+// WithPublicKey sets the public key of the signing key, which must match the private key if set.
+func WithPublicKey(pub *rsa.PublicKey) Option {
+	return func(c *Config) {
+		c.PublicKey = pub
+	}
+}
+
+// WithPermissionRequest limits the token access to the requested permissions,
+// including the access level of the single_file permission.
+func WithPermissionRequest(p PermissionRequest) Option {
+	return func(c *Config) {
+		c.SetPermissionRequest(p)
+	}
+}
+
+// WithIssuedAtSkew sets how far in the past the JWTs are issued, zero disabling it,
+// e.g. with clocks synchronized with PTP. By default DefaultIssuedAtSkew is used.
+func WithIssuedAtSkew(d time.Duration) Option {
+	return func(c *Config) {
+		c.IssuedAtSkew = d
+	}
+}
+
+// WithClock sets the clock driving the JWT claims, the token cache expiry and the retry deadlines.
+// It is primarily meant for deterministic tests; by default the real clock is used.
+// The retry delays are still waited for in real time.
+func WithClock(now func() time.Time) Option {
+	return func(c *Config) {
+		c.Clock = now
+	}
+}
+
+// WithKeyID sets the kid header of the signed JWTs.
+func WithKeyID(kid string) Option {
+	return func(c *Config) {
+		c.KeyID = kid
+	}
+}
+
+// WithFallbackKeys sets the keys used, in order, when GitHub rejects the JWTs
+// signed with the private key while fetching tokens.
+// During a key rotation, the new key can be registered on GitHub and deployed
+// as the private key with the old one as fallback, before the old one is deleted.
+func WithFallbackKeys(keys ...*rsa.PrivateKey) Option {
+	return func(c *Config) {
+		c.FallbackKeys = keys
+	}
+}
+
+// WithRetry enables the retries of failed token requests using the provided policy.
+func WithRetry(p RetryPolicy) Option {
+	return func(c *Config) {
+		c.Retry = p
+	}
+}
+
+// WithMaxElapsedTime bounds the total time spent fetching a token, including the retries.
+// It applies to the policy set with WithRetry, which must be provided before.
+func WithMaxElapsedTime(d time.Duration) Option {
+	return func(c *Config) {
+		c.Retry.MaxElapsedTime = d
+	}
+}
+
+// WithTokenStore sets the store used by the Installation configs
+// to reuse tokens across processes.
+func WithTokenStore(s TokenStore) Option {
+	return func(c *Config) {
+		c.Store = s
+	}
+}
+
+// WithStoreFailureMode sets how the token store failures are handled:
+// StoreFailOpen (the default) fetches tokens from GitHub, StoreFailClosed returns the failures.
+func WithStoreFailureMode(m StoreFailureMode) Option {
+	return func(c *Config) {
+		c.StoreFailureMode = m
+	}
+}
+
+// WithResponseHook sets a hook called with the status code and the body of each token response,
+// successful or not. The successful bodies contain the tokens: use it for debugging only.
+func WithResponseHook(hook func(statusCode int, body []byte)) Option {
+	return func(c *Config) {
+		c.ResponseHook = hook
+	}
+}
+
+// WithLogger sets the logger of the token requests.
+// By default nothing is logged.
+func WithLogger(l Logger) Option {
+	return func(c *Config) {
+		c.Logger = l
+	}
+}
+
+// WithTracer sets the tracer of the token requests.
+// By default no spans are started.
+func WithTracer(t Tracer) Option {
+	return func(c *Config) {
+		c.Tracer = t
+	}
+}
+
+// WithMetrics sets the metrics recorder of the token operations.
+// By default nothing is recorded.
+func WithMetrics(m Metrics) Option {
+	return func(c *Config) {
+		c.Metrics = m
+	}
+}
+
+// WithEarlyRefresh sets how long before their expiry the tokens are refreshed,
+// so that requests are not sent with tokens about to expire.
+func WithEarlyRefresh(d time.Duration) Option {
+	return func(c *Config) {
+		c.EarlyRefresh = d
+	}
+}
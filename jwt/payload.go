@@ -0,0 +1,91 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"crypto/rsa"
+	"sync"
+	"time"
+)
+
+// maxPayloadRefreshWindow is the maximum time before their expiry the cached payloads are re-signed.
+const maxPayloadRefreshWindow = time.Minute
+
+// timeNow returns the current time; it is replaced in tests.
+var timeNow = time.Now
+
+// payloadKey identifies the signed payloads which can be reused,
+// as the App JWTs are identical across the installations of an App.
+type payloadKey struct {
+	appID   string
+	keyID   string
+	expires time.Duration
+	skew    time.Duration
+	key     *rsa.PrivateKey
+}
+
+type signedPayload struct {
+	payload string
+	expiry  time.Time
+}
+
+// payloads caches the signed payloads across the JWTs of the process,
+// as RSA signing is expensive. The cache holds one entry per App key,
+// and the expired entries are dropped whenever a payload is cached.
+var payloads = struct {
+	mu      sync.Mutex
+	entries map[payloadKey]signedPayload
+}{entries: make(map[payloadKey]signedPayload)}
+
+// cachedPayload returns the payload cached for the key if it is not within the refresh window of its expiry.
+func cachedPayload(k payloadKey, now time.Time) (string, bool) {
+	payloads.mu.Lock()
+	defer payloads.mu.Unlock()
+	p, ok := payloads.entries[k]
+	if !ok || !now.Before(p.expiry.Add(-payloadRefreshWindow(k.expires))) {
+		return "", false
+	}
+	return p.payload, true
+}
+
+// cachePayload caches the payload for the key until its expiry,
+// dropping the expired payloads, e.g. of the configs which are no longer used.
+func cachePayload(k payloadKey, payload string, expiry, now time.Time) {
+	payloads.mu.Lock()
+	defer payloads.mu.Unlock()
+	for key, p := range payloads.entries {
+		if !now.Before(p.expiry) {
+			delete(payloads.entries, key)
+		}
+	}
+	payloads.entries[k] = signedPayload{payload: payload, expiry: expiry}
+}
+
+// forgetPayload removes the payload cached for the key, so that the next one is signed again.
+func forgetPayload(k payloadKey) {
+	payloads.mu.Lock()
+	defer payloads.mu.Unlock()
+	delete(payloads.entries, k)
+}
+
+// forgetKey removes the payloads cached for the private key, whatever the App and the expiry.
+func forgetKey(key *rsa.PrivateKey) {
+	payloads.mu.Lock()
+	defer payloads.mu.Unlock()
+	for k := range payloads.entries {
+		if k.key == key {
+			delete(payloads.entries, k)
+		}
+	}
+}
+
+// payloadRefreshWindow returns how long before their expiry the payloads are re-signed:
+// a minute, or half of the JWT lifetime for shorter lived JWTs.
+func payloadRefreshWindow(expires time.Duration) time.Duration {
+	if w := expires / 2; w < maxPayloadRefreshWindow {
+		return w
+	}
+	return maxPayloadRefreshWindow
+}
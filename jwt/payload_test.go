@@ -0,0 +1,166 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"crypto/rsa"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJWT_Payload_Cache(t *testing.T) {
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	key := getPrivateKey(t)
+	j := &JWT{AppID: "cache", PrivateKey: key, Expires: 10 * time.Minute}
+	first, err := j.Payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the payload is shared by the JWTs of the same App key
+	other := &JWT{AppID: "cache", PrivateKey: key, Expires: 10 * time.Minute}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p, err := other.Payload()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if p != first {
+				t.Error("payload was signed again; want the cached payload")
+			}
+		}()
+	}
+	wg.Wait()
+
+	kid := &JWT{AppID: "cache", PrivateKey: key, Expires: 10 * time.Minute, KeyID: "key-1"}
+	if p, err := kid.Payload(); err != nil || p == first {
+		t.Errorf("payload = %q, %v; want a payload with the kid header", p, err)
+	}
+
+	// within the refresh window of the expiry
+	now = now.Add(9*time.Minute + time.Second)
+	p, err := j.Payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == first {
+		t.Error("payload was reused within the refresh window of its expiry")
+	}
+}
+
+func TestJWT_SetKey(t *testing.T) {
+	oldKey, newKey := getPrivateKey(t), getPrivateKey(t)
+	shared := &atomic.Pointer[rsa.PrivateKey]{}
+	shared.Store(oldKey)
+	j := &JWT{AppID: "rotate", SharedKey: shared, Expires: 10 * time.Minute}
+	// a config derived with other options, sharing the key
+	derived := &JWT{AppID: "rotate", SharedKey: shared, Expires: 5 * time.Minute}
+	for _, v := range []*JWT{j, derived} {
+		if _, err := v.Payload(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := cachedPayloads(oldKey); n != 2 {
+		t.Fatalf("payloads cached for the old key = %d; want 2", n)
+	}
+
+	j.SetKey(newKey)
+	if n := cachedPayloads(oldKey); n != 0 {
+		t.Errorf("payloads cached for the old key = %d; want none", n)
+	}
+	if derived.Key() != newKey {
+		t.Error("the derived JWT should share the new key")
+	}
+
+	plain := &JWT{AppID: "rotate", PrivateKey: oldKey, Expires: 10 * time.Minute}
+	if _, err := plain.Payload(); err != nil {
+		t.Fatal(err)
+	}
+	plain.SetKey(newKey)
+	if plain.PrivateKey != newKey || cachedPayloads(oldKey) != 0 {
+		t.Error("the private key should be replaced and its payloads forgotten")
+	}
+}
+
+// cachedPayloads returns the number of payloads cached for the private key.
+func cachedPayloads(key *rsa.PrivateKey) int {
+	payloads.mu.Lock()
+	defer payloads.mu.Unlock()
+	n := 0
+	for k := range payloads.entries {
+		if k.key == key {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCachePayload_DropsExpired(t *testing.T) {
+	now := time.Now()
+	expired := payloadKey{appID: "expired", key: getPrivateKey(t)}
+	cachePayload(expired, "old", now.Add(time.Minute), now)
+
+	later := now.Add(time.Minute)
+	live := payloadKey{appID: "live", key: getPrivateKey(t)}
+	cachePayload(live, "new", later.Add(10*time.Minute), later)
+
+	if n := cachedPayloads(expired.key); n != 0 {
+		t.Errorf("payloads cached for the expired key = %d; want none", n)
+	}
+	if p, ok := cachedPayload(live, later); !ok || p != "new" {
+		t.Errorf("payload = %q, %t; want the live payload", p, ok)
+	}
+}
+
+func TestPayloadRefreshWindow(t *testing.T) {
+	tests := map[string]struct {
+		expires time.Duration
+		want    time.Duration
+	}{
+		"default": {expires: 10 * time.Minute, want: time.Minute},
+		"short":   {expires: time.Minute, want: 30 * time.Second},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := payloadRefreshWindow(tt.expires); got != tt.want {
+				t.Errorf("refresh window = %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkJWT_Payload(b *testing.B) {
+	j := &JWT{AppID: "bench", PrivateKey: getPrivateKey(b), Expires: 10 * time.Minute}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := j.Payload(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJWT_Payload_Uncached(b *testing.B) {
+	j := &JWT{AppID: "bench", PrivateKey: getPrivateKey(b), Expires: 10 * time.Minute}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		payloads.mu.Lock()
+		payloads.entries = make(map[payloadKey]signedPayload)
+		payloads.mu.Unlock()
+		if _, err := j.Payload(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrMissingExtra is returned when a token does not have the requested extra field,
+// e.g. tokens which were not fetched from GitHub.
+var ErrMissingExtra = errors.New("jwt: token extra field is missing")
+
+// singleFile is the permission to access a single file of the repositories.
+const singleFile = "single_file"
+
+// PermissionRequest is the subset of the App's permissions requested for a token.
+// See: https://docs.github.com/en/rest/apps/apps#create-an-installation-access-token-for-an-app
+type PermissionRequest struct {
+	// Permissions maps the permission names to the access levels, e.g. {"contents": "read"}.
+	Permissions map[string]string
+
+	// SingleFile is the optional access level of the single_file permission, read or write,
+	// requested as permissions.single_file. It overrides the one in Permissions, if any.
+	// The paths of the single file are set in the App registration, not per token.
+	SingleFile string
+}
+
+// permissions returns the requested permissions with the single_file access level, if any.
+func (p PermissionRequest) permissions() map[string]string {
+	if p.SingleFile == "" {
+		return p.Permissions
+	}
+	pp := make(map[string]string, len(p.Permissions)+1)
+	for name, level := range p.Permissions {
+		pp[name] = level
+	}
+	pp[singleFile] = p.SingleFile
+	return pp
+}
+
+// Permissions maps the permission names to the access levels, e.g. {"contents": "read"},
+// as granted to an App at registration or to an installation token.
+type Permissions map[string]string
+
+// accessLevels ranks the permission access levels, a higher level granting the lower ones.
+var accessLevels = map[string]int{"read": 1, "write": 2, "admin": 3}
+
+// Grants reports whether the permission is granted with at least the provided access level.
+// A write or admin access grants the read access, and an admin access grants the write access.
+// Unknown access levels only grant themselves.
+func (p Permissions) Grants(name, level string) bool {
+	got, ok := p[name]
+	if !ok {
+		return false
+	}
+	if got == level {
+		return true
+	}
+	g, ok := accessLevels[got]
+	w, known := accessLevels[level]
+	return ok && known && g >= w
+}
+
+// Missing returns the required permissions which are not granted, sorted,
+// e.g. ["contents: read, want write", "issues: missing, want read"].
+func (p Permissions) Missing(required map[string]string) []string {
+	var missing []string
+	for name, level := range required {
+		got, ok := p[name]
+		switch {
+		case !ok:
+			missing = append(missing, fmt.Sprintf("%s: missing, want %s", name, level))
+		case !p.Grants(name, level):
+			missing = append(missing, fmt.Sprintf("%s: %s, want %s", name, got, level))
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// ParsedPermissions returns the permissions granted to the installation token,
+// e.g. {"contents": "read", "issues": "write"}, as returned by GitHub with the token.
+func ParsedPermissions(t *oauth2.Token) (map[string]string, error) {
+	if t == nil {
+		return nil, fmt.Errorf("permissions: %w", ErrMissingExtra)
+	}
+	switch extra := t.Extra("permissions").(type) {
+	case nil:
+		return nil, fmt.Errorf("permissions: %w", ErrMissingExtra)
+	case map[string]string:
+		return extra, nil
+	case map[string]interface{}:
+		pp := make(map[string]string, len(extra))
+		for k, v := range extra {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("jwt: invalid permission %q: %v", k, v)
+			}
+			pp[k] = s
+		}
+		return pp, nil
+	default:
+		return nil, fmt.Errorf("jwt: invalid permissions: %v", extra)
+	}
+}
+
+// ParsedRepositorySelection returns the repository selection of the installation token,
+// either all or selected, as returned by GitHub with the token.
+func ParsedRepositorySelection(t *oauth2.Token) (string, error) {
+	if t == nil {
+		return "", fmt.Errorf("repository_selection: %w", ErrMissingExtra)
+	}
+	switch extra := t.Extra("repository_selection").(type) {
+	case nil:
+		return "", fmt.Errorf("repository_selection: %w", ErrMissingExtra)
+	case string:
+		return extra, nil
+	default:
+		return "", fmt.Errorf("jwt: invalid repository selection: %v", extra)
+	}
+}
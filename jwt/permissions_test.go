@@ -0,0 +1,108 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestParsedPermissions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "permissions": {"contents": "read", "issues": "write"}, "repository_selection": "selected"}`))
+	}))
+	defer ts.Close()
+
+	conf := &Config{JWT: JWT{AppID: "1", PrivateKey: getPrivateKey(t)}, TokenURL: ts.URL}
+	token, err := conf.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := ParsedPermissions(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pp) != 2 || pp["contents"] != "read" || pp["issues"] != "write" {
+		t.Errorf("permissions = %v; want contents:read and issues:write", pp)
+	}
+	rs, err := ParsedRepositorySelection(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rs, "selected"; got != want {
+		t.Errorf("repository selection = %q; want %q", got, want)
+	}
+}
+
+func TestParsedPermissions_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		token   *oauth2.Token
+		missing bool
+	}{
+		"nil token":      {missing: true},
+		"missing extras": {token: &oauth2.Token{AccessToken: "x"}, missing: true},
+		"invalid type": {token: (&oauth2.Token{AccessToken: "x"}).WithExtra(map[string]interface{}{
+			"permissions": "read", "repository_selection": 1,
+		})},
+		"invalid value": {token: (&oauth2.Token{AccessToken: "x"}).WithExtra(map[string]interface{}{
+			"permissions": map[string]interface{}{"contents": 1}, "repository_selection": true,
+		})},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParsedPermissions(tt.token)
+			if err == nil || errors.Is(err, ErrMissingExtra) != tt.missing {
+				t.Errorf("permissions error = %v; want missing %t", err, tt.missing)
+			}
+			_, err = ParsedRepositorySelection(tt.token)
+			if err == nil || errors.Is(err, ErrMissingExtra) != tt.missing {
+				t.Errorf("repository selection error = %v; want missing %t", err, tt.missing)
+			}
+		})
+	}
+}
+
+func TestPermissions_Grants(t *testing.T) {
+	p := Permissions{"contents": "read", "issues": "write", "administration": "admin", "custom": "maintain"}
+	tests := map[string]struct {
+		name, level string
+		want        bool
+	}{
+		"same level":     {name: "contents", level: "read", want: true},
+		"higher level":   {name: "contents", level: "write"},
+		"write grants":   {name: "issues", level: "read", want: true},
+		"admin grants":   {name: "administration", level: "write", want: true},
+		"missing":        {name: "pull_requests", level: "read"},
+		"unknown level":  {name: "custom", level: "maintain", want: true},
+		"unknown grants": {name: "custom", level: "read"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := p.Grants(tt.name, tt.level); got != tt.want {
+				t.Errorf("Grants(%q, %q) = %t; want %t", tt.name, tt.level, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPermissions_Missing(t *testing.T) {
+	p := Permissions{"contents": "read", "issues": "write"}
+	got := p.Missing(map[string]string{"contents": "write", "issues": "read", "pull_requests": "read"})
+	want := []string{"contents: read, want write", "pull_requests: missing, want read"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("missing = %q; want %q", got, want)
+	}
+	if got := p.Missing(map[string]string{"issues": "write"}); got != nil {
+		t.Errorf("missing = %q; want none", got)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// rateLimitExtra is the token extra field holding the rate limit of the token response.
+const rateLimitExtra = "github_auth_rate_limit"
+
+// RateLimit is the GitHub API rate limit status reported by a response.
+// See: https://docs.github.com/en/rest/overview/resources-in-the-rest-api#rate-limiting
+type RateLimit struct {
+	// Limit is the maximum number of requests per hour.
+	Limit int
+
+	// Remaining is the number of requests remaining in the current window.
+	Remaining int
+
+	// Reset is the time at which the current window resets.
+	Reset time.Time
+}
+
+// parseRateLimit parses the X-RateLimit-* headers of a response.
+// It reports false if the headers are missing.
+func parseRateLimit(h http.Header) (RateLimit, bool) {
+	limit, err := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+	rl := RateLimit{Limit: limit}
+	rl.Remaining, _ = strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if s, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		rl.Reset = time.Unix(s, 0)
+	}
+	return rl, true
+}
+
+// RateLimitFromToken returns the rate limit reported by the response the token was fetched with.
+// It reports false if the response had no rate limit headers.
+func RateLimitFromToken(t *oauth2.Token) (RateLimit, bool) {
+	if t == nil {
+		return RateLimit{}, false
+	}
+	rl, ok := t.Extra(rateLimitExtra).(RateLimit)
+	return rl, ok
+}
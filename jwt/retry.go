@@ -0,0 +1,140 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// RetryPolicy configures the retries of failed token requests.
+// The zero value disables retries.
+//
+// Only transient failures are retried: server errors (500, 502, 503, 504),
+// 429 and 403 responses caused by secondary rate limits.
+// A 401 response rejecting the JWT as it could not be decoded, e.g. on clock skew,
+// is retried once without delay with a newly signed JWT.
+// Rate limited requests are retried after the time indicated by GitHub,
+// capped by MaxDelay (or one minute if MaxDelay is not set).
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	// Values lower than 2 disable retries.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry.
+	// The delay is doubled on every subsequent retry.
+	BaseDelay time.Duration
+
+	// MaxDelay optionally caps the delay between two attempts.
+	MaxDelay time.Duration
+
+	// MaxElapsedTime optionally bounds the total time spent fetching a token, including the waits.
+	// No retry is attempted when its wait would exceed it, and the last error is returned.
+	MaxElapsedTime time.Duration
+
+	// FullJitter optionally randomizes the whole delay between two attempts, instead of its second half,
+	// to spread the retries of many installations refreshing at once.
+	FullJitter bool
+}
+
+func (p RetryPolicy) enabled() bool {
+	return p.MaxAttempts > 1
+}
+
+// maxRetryAfter caps the wait for rate limited requests when MaxDelay is not set.
+const maxRetryAfter = time.Minute
+
+// wait returns how long to wait before retrying after the provided attempt failed with err at now.
+func (p RetryPolicy) wait(attempt int, err error, now time.Time) time.Duration {
+	var rle *RateLimitError
+	if !errors.As(err, &rle) || rle.Reset.IsZero() {
+		return p.delay(attempt)
+	}
+	max := p.MaxDelay
+	if max <= 0 {
+		max = maxRetryAfter
+	}
+	d := rle.Reset.Sub(now)
+	if d > max {
+		return max
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// delay returns the backoff delay, with jitter, after the provided attempt.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	if p.FullJitter {
+		return time.Duration(rand.Int63n(int64(d) + 1))
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// exceeds reports whether waiting d after elapsed would exceed the maximum elapsed time.
+func (p RetryPolicy) exceeds(elapsed, d time.Duration) bool {
+	return p.MaxElapsedTime > 0 && elapsed+d > p.MaxElapsedTime
+}
+
+// retryable reports whether the error is caused by a transient failure.
+func retryable(err error) bool {
+	var re *oauth2.RetrieveError
+	if !errors.As(err, &re) || re.Response == nil {
+		return false
+	}
+	if errors.Is(err, ErrInstallationSuspended) {
+		// suspended installations fail until they are unsuspended
+		return false
+	}
+	switch re.Response.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout, http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		return rateLimited(re.Response) ||
+			bytes.Contains(bytes.ToLower(re.Body), []byte("secondary rate limit"))
+	}
+	return false
+}
+
+// undecodableJWT reports whether GitHub rejected the JWT as it could not be decoded,
+// which is usually caused by an expired JWT or a clock skew rather than by an invalid key.
+// GitHub words it "A JSON web token could not be decoded", or "A JWT could not be decoded".
+func undecodableJWT(err error) bool {
+	var ae *AuthError
+	return errors.As(err, &ae) && ae.StatusCode == http.StatusUnauthorized &&
+		strings.Contains(strings.ToLower(ae.Message), "could not be decoded")
+}
+
+// sleep waits for the provided duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,343 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/beatlabs/github-auth/githubtest"
+	"github.com/beatlabs/github-auth/jws"
+	"golang.org/x/oauth2"
+)
+
+func TestRetry(t *testing.T) {
+	tests := map[string]struct {
+		status    int
+		body      string
+		wantCalls int
+		wantErr   bool
+	}{
+		"server error":         {status: http.StatusServiceUnavailable, wantCalls: 3},
+		"secondary rate limit": {status: http.StatusForbidden, body: `{"message": "You have exceeded a secondary rate limit."}`, wantCalls: 3},
+		"unauthorized":         {status: http.StatusUnauthorized, wantCalls: 1, wantErr: true},
+		"unprocessable":        {status: http.StatusUnprocessableEntity, wantCalls: 1, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.Header().Set("Content-Type", "application/json")
+				if calls < 3 {
+					w.WriteHeader(tt.status)
+					//nolint:errcheck
+					w.Write([]byte(tt.body))
+					return
+				}
+				//nolint:errcheck
+				w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+			}))
+			defer ts.Close()
+
+			conf := &Config{
+				JWT: JWT{
+					AppID:      "1",
+					PrivateKey: getPrivateKey(t),
+				},
+				TokenURL: ts.URL,
+				Retry:    RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond},
+			}
+			_, err := conf.TokenSource(context.Background()).Token()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v; want error %t", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d; want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetry_ContextDone(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	conf := &Config{
+		JWT: JWT{
+			AppID:      "1",
+			PrivateKey: getPrivateKey(t),
+		},
+		TokenURL: ts.URL,
+		Retry:    RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour},
+	}
+	_, err := conf.TokenSource(ctx).Token()
+	if err == nil {
+		t.Fatal("got no error, expected one")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d; want 1", calls)
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, max := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 10: time.Second} {
+		d := p.delay(attempt)
+		if d < max/2 || d > max {
+			t.Errorf("delay(%d) = %v; want within [%v, %v]", attempt, d, max/2, max)
+		}
+	}
+}
+
+func TestRetryPolicy_FullJitter(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, FullJitter: true}
+	for attempt, max := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 10: time.Second} {
+		for i := 0; i < 100; i++ {
+			if d := p.delay(attempt); d < 0 || d > max {
+				t.Fatalf("delay(%d) = %v; want within [0, %v]", attempt, d, max)
+			}
+		}
+	}
+}
+
+func TestRetry_MaxElapsedTime(t *testing.T) {
+	// each request takes a minute on the fake clock
+	var now atomic.Int64
+	now.Store(time.Now().UnixNano())
+	timeNow = func() time.Time { return time.Unix(0, now.Load()) }
+	defer func() { timeNow = time.Now }()
+
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		now.Add(int64(time.Minute))
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	conf := &Config{
+		JWT:      JWT{AppID: "1", PrivateKey: getPrivateKey(t)},
+		TokenURL: ts.URL,
+	}
+	WithRetry(RetryPolicy{MaxAttempts: 10, BaseDelay: time.Millisecond, FullJitter: true})(conf)
+	WithMaxElapsedTime(150 * time.Second)(conf)
+
+	_, err := conf.Token(context.Background())
+	var ae *AuthError
+	if !errors.As(err, &ae) || ae.StatusCode != http.StatusBadGateway {
+		t.Errorf("error = %v; want the last 502 error", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d; want 3 within the maximum elapsed time", calls)
+	}
+}
+
+func TestRetry_Clock(t *testing.T) {
+	// each request takes a minute on the injected clock
+	now := time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC)
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		now = now.Add(time.Minute)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	conf := &Config{
+		JWT:      JWT{AppID: "1", PrivateKey: getPrivateKey(t)},
+		TokenURL: ts.URL,
+	}
+	WithClock(func() time.Time { return now })(conf)
+	WithRetry(RetryPolicy{MaxAttempts: 10, BaseDelay: time.Millisecond, MaxElapsedTime: 150 * time.Second})(conf)
+
+	if _, err := conf.Token(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d; want 3 within the maximum elapsed time", calls)
+	}
+}
+
+func TestRetry_UndecodableJWT(t *testing.T) {
+	const body = `{"message":"A JWT could not be decoded","documentation_url":"https://docs.github.com/rest"}`
+	tests := map[string]struct {
+		failures  int
+		retry     RetryPolicy
+		fallback  bool
+		wantCalls int
+		wantErr   bool
+	}{
+		"re-signed":        {failures: 1, retry: RetryPolicy{MaxAttempts: 3}, wantCalls: 2},
+		"fallback skipped": {failures: 1, retry: RetryPolicy{MaxAttempts: 3}, fallback: true, wantCalls: 2},
+		"re-signed once":   {failures: 3, retry: RetryPolicy{MaxAttempts: 3}, wantCalls: 2, wantErr: true},
+		"retries disabled": {failures: 1, wantCalls: 1, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			now := time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC)
+			key := getPrivateKey(t)
+			var auths []string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auths = append(auths, r.Header.Get("Authorization"))
+				now = now.Add(time.Second)
+				// only the private key is registered on GitHub
+				signed := jws.Verify(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), &key.PublicKey) == nil
+				if len(auths) <= tt.failures || !signed {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusUnauthorized)
+					//nolint:errcheck
+					w.Write([]byte(body))
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				//nolint:errcheck
+				w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+			}))
+			defer ts.Close()
+
+			conf := &Config{
+				JWT:      JWT{AppID: "1", PrivateKey: key, Expires: 10 * time.Minute},
+				TokenURL: ts.URL,
+				Retry:    tt.retry,
+			}
+			if tt.fallback {
+				// a key which is not registered on GitHub
+				other, err := rsa.GenerateKey(rand.Reader, 2048)
+				if err != nil {
+					t.Fatal(err)
+				}
+				WithFallbackKeys(other)(conf)
+			}
+			WithClock(func() time.Time { return now })(conf)
+
+			_, err := conf.Token(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v; want error %t", err, tt.wantErr)
+			}
+			if len(auths) != tt.wantCalls {
+				t.Fatalf("calls = %d; want %d", len(auths), tt.wantCalls)
+			}
+			if len(auths) == 2 && auths[0] == auths[1] {
+				t.Error("the JWT was not signed again")
+			}
+		})
+	}
+}
+
+func TestRetry_UndecodableJWT_GitHubTest(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	// the response of GitHub to a JWT which could not be decoded
+	srv.SetError(http.StatusUnauthorized, "A JSON web token could not be decoded")
+
+	now := time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC)
+	conf := &Config{
+		JWT:      JWT{AppID: "1", PrivateKey: getPrivateKey(t), Expires: 10 * time.Minute},
+		TokenURL: srv.URL + "/app/installations/2/access_tokens",
+		Retry:    RetryPolicy{MaxAttempts: 3},
+	}
+	WithClock(func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	})(conf)
+
+	_, err := conf.Token(context.Background())
+	var ae *AuthError
+	if !errors.As(err, &ae) || ae.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("error = %v; want a 401 *AuthError", err)
+	}
+	reqs := srv.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("requests = %d; want 2, the JWT signed again once", len(reqs))
+	}
+	if reqs[0].Authorization == reqs[1].Authorization {
+		t.Error("the JWT was not signed again")
+	}
+}
+
+func TestRetry_RetryAfter(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	conf := &Config{
+		JWT: JWT{
+			AppID:      "1",
+			PrivateKey: getPrivateKey(t),
+		},
+		TokenURL: ts.URL,
+		Retry:    RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond},
+	}
+	start := time.Now()
+	_, err := conf.TokenSource(context.Background()).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("elapsed = %v; want at least the Retry-After delay", elapsed)
+	}
+}
+
+func TestRateLimitError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", "2524608000")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	conf := &Config{
+		JWT: JWT{
+			AppID:      "1",
+			PrivateKey: getPrivateKey(t),
+		},
+		TokenURL: ts.URL,
+	}
+	_, err := conf.TokenSource(context.Background()).Token()
+	var rle *RateLimitError
+	if !errors.As(err, &rle) {
+		t.Fatalf("got %T error, expected *RateLimitError", err)
+	}
+	if got, want := rle.Reset, time.Unix(2524608000, 0); !got.Equal(want) {
+		t.Errorf("reset = %v; want %v", got, want)
+	}
+	var re *oauth2.RetrieveError
+	if !errors.As(err, &re) {
+		t.Errorf("got %T error, expected to wrap *RetrieveError", err)
+	}
+}
+
+func TestRetryable_Suspended(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{"X-Ratelimit-Remaining": []string{"0"}}}
+	body := []byte(`{"message":"This installation has been suspended","documentation_url":"https://docs.github.com/rest/apps/apps#create-an-installation-access-token-for-an-app","status":"403"}`)
+	if retryable(newAuthError(resp, body)) {
+		t.Error("suspended installation errors should not be retryable")
+	}
+}
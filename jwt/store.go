@@ -0,0 +1,89 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// TokenStore stores installation tokens so that they can be reused
+// across processes (e.g. multiple replicas or serverless invocations).
+// Tokens are keyed by installation ID.
+//
+// Implementations must be safe for concurrent use.
+// Tokens grant access to the installation, so stores should encrypt them at rest.
+type TokenStore interface {
+	// Get returns the token stored for the key, or nil if there is none.
+	Get(ctx context.Context, key string) (*oauth2.Token, error)
+
+	// Set stores the token for the key.
+	Set(ctx context.Context, key string, token *oauth2.Token) error
+
+	// Delete removes the token stored for the key.
+	Delete(ctx context.Context, key string) error
+}
+
+// StoreFailureMode defines how the token store failures are handled.
+type StoreFailureMode int
+
+const (
+	// StoreFailOpen ignores the token store failures for availability:
+	// the tokens are fetched from GitHub and only cached in memory. It is the default.
+	StoreFailOpen StoreFailureMode = iota
+
+	// StoreFailClosed returns the token store failures, matching ErrTokenStore,
+	// instead of fetching or returning tokens which are not stored.
+	StoreFailClosed
+)
+
+// ErrTokenStore is matched by the token store failures returned with StoreFailClosed.
+var ErrTokenStore = errors.New("jwt: token store failed")
+
+// MemoryStore is an in-memory TokenStore.
+// It can be shared by the Installation configs of a process.
+type MemoryStore struct {
+	mu     sync.Mutex
+	tokens map[string]*oauth2.Token
+}
+
+// NewMemoryStore returns a new in-memory TokenStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tokens: make(map[string]*oauth2.Token)}
+}
+
+// Get returns the token stored for the key, or nil if there is none or it has expired.
+func (s *MemoryStore) Get(_ context.Context, key string) (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tokens[key]
+	if !ok {
+		return nil, nil
+	}
+	if !t.Valid() {
+		delete(s.tokens, key)
+		return nil, nil
+	}
+	return t, nil
+}
+
+// Set stores the token for the key.
+func (s *MemoryStore) Set(_ context.Context, key string, token *oauth2.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[key] = token
+	return nil
+}
+
+// Delete removes the token stored for the key.
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, key)
+	return nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"context"
+)
+
+// Tracer starts the tracing spans of the token requests.
+// The github.com/beatlabs/github-auth/tracing module provides an OpenTelemetry implementation.
+type Tracer interface {
+	// Start starts a span as a child of the span in the context, if any.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a tracing span.
+type Span interface {
+	// SetAttributes sets the alternating keys and values as span attributes.
+	SetAttributes(keysAndValues ...interface{})
+
+	// End ends the span, recording the error if not nil.
+	End(err error)
+}
+
+// TokenSpanName is the name of the token request spans.
+const TokenSpanName = "github-auth.token"
+
+// nopTracer is the default Tracer, starting no spans.
+type nopTracer struct{}
+
+func (nopTracer) Start(ctx context.Context, _ string) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(...interface{}) {}
+func (nopSpan) End(error)                    {}
+
+func (c *Config) tracer() Tracer {
+	if c.Tracer == nil {
+		return nopTracer{}
+	}
+	return c.Tracer
+}
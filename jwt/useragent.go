@@ -0,0 +1,39 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+import "runtime/debug"
+
+// modulePath is the path of the module, used to find its version in the build info.
+const modulePath = "github.com/beatlabs/github-auth"
+
+// DefaultUserAgent is the User-Agent header of the requests when none is configured,
+// e.g. github-auth/v1.2.0, with the module version of the binary build info if known.
+var DefaultUserAgent = "github-auth/" + moduleVersion()
+
+// moduleVersion returns the version of the module, or devel if it is unknown.
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	if info.Main.Path == modulePath && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath && dep.Version != "" {
+			return dep.Version
+		}
+	}
+	return "devel"
+}
+
+// userAgent returns the User-Agent header of the requests.
+func (j *JWT) userAgent() string {
+	if j.UserAgent != "" {
+		return j.UserAgent
+	}
+	return DefaultUserAgent
+}
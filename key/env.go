@@ -0,0 +1,54 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FromEnv loads a private key from the value of the environment variable, PEM or base64 encoded,
+// e.g. FromEnv("PRIVATE_KEY"). It fails if the variable is not set.
+func FromEnv(name string) (*rsa.PrivateKey, error) {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", name)
+	}
+	data := []byte(raw)
+	if !bytes.HasPrefix(data, []byte("-----BEGIN")) {
+		decoded, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil {
+			return nil, fmt.Errorf("environment variable %s is neither PEM nor base64 encoded: %w", name, err)
+		}
+		data = decoded
+	}
+	key, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("environment variable %s: %w", name, err)
+	}
+	return key, nil
+}
+
+// FromFileOrEnv loads a private key from the environment, e.g. FromFileOrEnv("PRIVATE_KEY_PATH", "PRIVATE_KEY"):
+// from the file at the path held by the pathEnv variable if it is set,
+// otherwise from the value of the valueEnv variable, see FromEnv.
+// It fails if neither variable is set.
+func FromFileOrEnv(pathEnv, valueEnv string) (*rsa.PrivateKey, error) {
+	if path := os.Getenv(pathEnv); path != "" {
+		key, err := FromFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("environment variable %s: %w", pathEnv, err)
+		}
+		return key, nil
+	}
+	if strings.TrimSpace(os.Getenv(valueEnv)) == "" {
+		return nil, fmt.Errorf("failed to load private key: neither environment variable %s nor %s is set", pathEnv, valueEnv)
+	}
+	return FromEnv(valueEnv)
+}
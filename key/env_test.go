@@ -0,0 +1,60 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFromFileOrEnv(t *testing.T) {
+	key, pemKey := encodedKey(t)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pemKey, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		path, value string
+		wantErr     string
+	}{
+		"path":             {path: path},
+		"path precedence":  {path: path, value: "invalid"},
+		"base64 value":     {value: base64.StdEncoding.EncodeToString(pemKey)},
+		"PEM value":        {value: string(pemKey)},
+		"missing file":     {path: filepath.Join(t.TempDir(), "missing.pem"), wantErr: "environment variable TEST_KEY_PATH: failed to read private key"},
+		"invalid value":    {value: "not a key!", wantErr: "environment variable TEST_KEY is neither PEM nor base64 encoded"},
+		"neither variable": {wantErr: "failed to load private key: neither environment variable TEST_KEY_PATH nor TEST_KEY is set"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("TEST_KEY_PATH", tt.path)
+			t.Setenv("TEST_KEY", tt.value)
+			got, err := FromFileOrEnv("TEST_KEY_PATH", "TEST_KEY")
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v; want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(key) {
+				t.Error("the loaded key does not match")
+			}
+		})
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("TEST_KEY", "")
+	if _, err := FromEnv("TEST_KEY"); err == nil || err.Error() != "environment variable TEST_KEY is not set" {
+		t.Errorf("error = %v; want a not set error", err)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+)
+
+// JWK is the JSON Web Key of an RSA public key used to verify the signed JWTs.
+// See: https://www.rfc-editor.org/rfc/rfc7517
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+}
+
+// JWKSet is a JSON Web Key Set document.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWK returns the JWK of the public key with the provided key ID.
+// If the key ID is empty, the Thumbprint of the key is used.
+// The public key of a private key is &key.PublicKey.
+func NewJWK(pub *rsa.PublicKey, kid string) JWK {
+	if kid == "" {
+		kid = Thumbprint(pub)
+	}
+	return JWK{
+		KeyType:   "RSA",
+		KeyID:     kid,
+		Use:       "sig",
+		Algorithm: "RS256",
+		N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
+
+// JWKS returns the JSON Web Key Set document of the provided keys,
+// e.g. to publish both the current and the next key during a rotation.
+func JWKS(keys ...JWK) ([]byte, error) {
+	if keys == nil {
+		keys = []JWK{}
+	}
+	return json.Marshal(JWKSet{Keys: keys})
+}
+
+// Thumbprint returns the RFC 7638 SHA-256 thumbprint of the public key,
+// which is a stable key ID.
+func Thumbprint(pub *rsa.PublicKey) string {
+	// the required members in lexicographic order, without whitespace
+	b, _ := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		Kty: "RSA",
+		N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+	})
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestThumbprint(t *testing.T) {
+	// See: https://www.rfc-editor.org/rfc/rfc7638#section-3.1
+	n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}
+	if got, want := Thumbprint(pub), "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; got != want {
+		t.Errorf("thumbprint = %q; want %q", got, want)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := JWKS(NewJWK(&key.PublicKey, "key-1"), NewJWK(&key.PublicKey, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var set JWKSet
+	if err := json.Unmarshal(doc, &set); err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Keys) != 2 {
+		t.Fatalf("keys = %d; want 2", len(set.Keys))
+	}
+	if got, want := set.Keys[0].KeyID, "key-1"; got != want {
+		t.Errorf("kid = %q; want %q", got, want)
+	}
+	if got, want := set.Keys[1].KeyID, Thumbprint(&key.PublicKey); got != want {
+		t.Errorf("kid = %q; want the thumbprint %q", got, want)
+	}
+	jwk := set.Keys[0]
+	if jwk.KeyType != "RSA" || jwk.Algorithm != "RS256" || jwk.Use != "sig" {
+		t.Errorf("jwk = %+v; want an RSA RS256 signature key", jwk)
+	}
+	if got, want := jwk.E, "AQAB"; got != want {
+		t.Errorf("e = %q; want %q", got, want)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if new(big.Int).SetBytes(n).Cmp(key.N) != 0 {
+		t.Error("n does not match the public key modulus")
+	}
+
+	doc, err = JWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(doc), `{"keys":[]}`; got != want {
+		t.Errorf("empty JWKS = %s; want %s", got, want)
+	}
+}
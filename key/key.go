@@ -3,15 +3,38 @@ package key
 import (
 	"crypto/rsa"
 	"fmt"
+	"io"
 	"os"
 )
 
+// MaxSize is the maximum size of the private keys read by FromFile and FromReader.
+// GitHub App keys are about 2KB; the limit stops reading from unbounded sources.
+const MaxSize = 64 << 10
+
 // FromFile loads a private key from the provided path and parses it.
 // The private key is returned if parsing succeeds.
+// The path can also be a named pipe or a file descriptor (e.g. /dev/fd/3),
+// which is read until EOF.
 func FromFile(path string) (*rsa.PrivateKey, error) {
-	key, err := os.ReadFile(path)
+	f, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read private key: %s", err.Error())
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+	defer f.Close()
+
+	return FromReader(f)
+}
+
+// FromReader reads a private key until EOF and parses it.
+// Short reads, as returned by pipes, are retried until EOF,
+// and keys larger than MaxSize are rejected.
+func FromReader(r io.Reader) (*rsa.PrivateKey, error) {
+	key, err := io.ReadAll(io.LimitReader(r, MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+	if len(key) > MaxSize {
+		return nil, fmt.Errorf("failed to read private key: larger than %d bytes", MaxSize)
 	}
 
 	return Parse(key)
@@ -0,0 +1,75 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func encodedKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func TestFromReader(t *testing.T) {
+	key, pemKey := encodedKey(t)
+	tests := map[string]struct {
+		data    []byte
+		wantErr string
+	}{
+		"short reads": {data: pemKey},
+		"too large":   {data: bytes.Repeat([]byte("a"), MaxSize+1), wantErr: "failed to read private key: larger than 65536 bytes"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := FromReader(iotest.OneByteReader(bytes.NewReader(tt.data)))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("error = %v; want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(key) {
+				t.Error("the parsed key does not match")
+			}
+		})
+	}
+}
+
+func TestFromFile(t *testing.T) {
+	key, pemKey := encodedKey(t)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pemKey, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := FromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(key) {
+		t.Error("the parsed key does not match")
+	}
+
+	_, err = FromFile(filepath.Join(t.TempDir(), "missing.pem"))
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to read private key: ") {
+		t.Errorf("error = %v; want a read error", err)
+	}
+}
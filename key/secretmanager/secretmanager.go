@@ -0,0 +1,131 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package secretmanager implements private keys stored in Google Cloud Secret Manager.
+package secretmanager
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/beatlabs/github-auth/key"
+)
+
+// DefaultBaseURL is the default base URL of the Secret Manager API.
+const DefaultBaseURL = "https://secretmanager.googleapis.com/v1/"
+
+var (
+	// ErrNotFound is returned when the secret or its version does not exist.
+	ErrNotFound = errors.New("secretmanager: secret not found")
+	// ErrPermissionDenied is returned when the caller cannot access the secret version.
+	ErrPermissionDenied = errors.New("secretmanager: permission denied")
+)
+
+// Client accesses secret versions with the Secret Manager REST API.
+// See: https://cloud.google.com/secret-manager/docs/reference/rest/v1/projects.secrets.versions/access
+type Client struct {
+	// HTTPClient is the HTTP client of the requests, authenticated with Google credentials,
+	// e.g. the client returned by google.DefaultClient of golang.org/x/oauth2/google.
+	// If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// BaseURL optionally specifies the base URL of the API.
+	// If empty, DefaultBaseURL is used.
+	BaseURL string
+}
+
+// maxBodySize is the maximum size of the response bodies read.
+const maxBodySize = 1 << 20
+
+// Secret returns a key source reading the PEM private key from a secret version,
+// e.g. projects/my-project/secrets/github-app/versions/3.
+// Secret names without a version, e.g. projects/my-project/secrets/github-app,
+// read the latest version.
+func (c *Client) Secret(name string) key.Source {
+	if !strings.Contains(name, "/versions/") {
+		name = strings.TrimSuffix(name, "/") + "/versions/latest"
+	}
+	return key.SourceFunc(func(ctx context.Context) ([]byte, error) {
+		return c.access(ctx, name)
+	})
+}
+
+// access returns the payload of the secret version.
+func (c *Client) access(ctx context.Context, name string) ([]byte, error) {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(base, "/")+"/"+name+":access", nil)
+	if err != nil {
+		return nil, err
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, responseError(name, resp.StatusCode, body)
+	}
+
+	var res struct {
+		Payload struct {
+			Data       string `json:"data"`
+			DataCrc32c string `json:"dataCrc32c"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(res.Payload.Data)
+	if err != nil {
+		return nil, fmt.Errorf("secretmanager: invalid payload of %s: %w", name, err)
+	}
+	if res.Payload.DataCrc32c != "" {
+		want, err := strconv.ParseUint(res.Payload.DataCrc32c, 10, 32)
+		if err != nil || crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)) != uint32(want) {
+			return nil, fmt.Errorf("secretmanager: corrupted payload of %s", name)
+		}
+	}
+	return data, nil
+}
+
+// responseError returns the error of a failed access, wrapping ErrNotFound or ErrPermissionDenied.
+func responseError(name string, code int, body []byte) error {
+	var res struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	//nolint:errcheck
+	json.Unmarshal(body, &res) // the error body is optional
+	msg := res.Error.Message
+	if msg == "" {
+		msg = http.StatusText(code)
+	}
+	switch code {
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s: %s", ErrNotFound, name, msg)
+	case http.StatusForbidden:
+		return fmt.Errorf("%w: %s: %s", ErrPermissionDenied, name, msg)
+	}
+	return fmt.Errorf("secretmanager: %s: %d %s", name, code, msg)
+}
@@ -0,0 +1,92 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package secretmanager
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/beatlabs/github-auth/key"
+)
+
+func TestClient_Secret(t *testing.T) {
+	pk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(pk)})
+	crc := strconv.FormatUint(uint64(crc32.Checksum(pemKey, crc32.MakeTable(crc32.Castagnoli))), 10)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/projects/p/secrets/app/versions/latest:access", "/v1/projects/p/secrets/app/versions/2:access":
+			//nolint:errcheck
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"name":    "projects/p/secrets/app/versions/2",
+				"payload": map[string]string{"data": base64.StdEncoding.EncodeToString(pemKey), "dataCrc32c": crc},
+			})
+		case "/v1/projects/p/secrets/corrupted/versions/latest:access":
+			//nolint:errcheck
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"payload": map[string]string{"data": base64.StdEncoding.EncodeToString(pemKey), "dataCrc32c": "1"},
+			})
+		case "/v1/projects/p/secrets/denied/versions/latest:access":
+			w.WriteHeader(http.StatusForbidden)
+			//nolint:errcheck
+			w.Write([]byte(`{"error": {"code": 403, "message": "Permission 'secretmanager.versions.access' denied", "status": "PERMISSION_DENIED"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			//nolint:errcheck
+			w.Write([]byte(`{"error": {"code": 404, "message": "Secret not found", "status": "NOT_FOUND"}}`))
+		}
+	}))
+	defer ts.Close()
+
+	c := &Client{BaseURL: ts.URL + "/v1/"}
+
+	tests := map[string]struct {
+		name    string
+		wantErr error
+	}{
+		"latest":            {name: "projects/p/secrets/app"},
+		"pinned":            {name: "projects/p/secrets/app/versions/2"},
+		"not found":         {name: "projects/p/secrets/missing", wantErr: ErrNotFound},
+		"permission denied": {name: "projects/p/secrets/denied", wantErr: ErrPermissionDenied},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := key.FromSource(context.Background(), c.Secret(tt.name))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v; want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(pk) {
+				t.Error("the loaded key does not match")
+			}
+		})
+	}
+
+	_, err = c.Secret("projects/p/secrets/corrupted").Load(context.Background())
+	if err == nil || errors.Is(err, ErrNotFound) || errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("error = %v; want a corrupted payload error", err)
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"context"
+	"crypto/rsa"
+	"fmt"
+)
+
+// Source loads the contents of a private key, e.g. from a secret manager.
+// The context controls the cancellation and the deadline of the loading,
+// which can be a network call.
+type Source interface {
+	Load(ctx context.Context) ([]byte, error)
+}
+
+// SourceFunc adapts a function to a Source.
+type SourceFunc func(ctx context.Context) ([]byte, error)
+
+// Load calls f(ctx).
+func (f SourceFunc) Load(ctx context.Context) ([]byte, error) {
+	return f(ctx)
+}
+
+// FromSource loads a private key from the provided source and parses it.
+// The private key is returned if parsing succeeds.
+func FromSource(ctx context.Context, s Source) (*rsa.PrivateKey, error) {
+	key, err := s.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load private key: %w", err)
+	}
+
+	return Parse(key)
+}
@@ -0,0 +1,33 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFromSource(t *testing.T) {
+	key, pemKey := encodedKey(t)
+	got, err := FromSource(context.Background(), SourceFunc(func(ctx context.Context) ([]byte, error) {
+		return pemKey, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(key) {
+		t.Error("the parsed key does not match")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = FromSource(ctx, SourceFunc(func(ctx context.Context) ([]byte, error) {
+		return nil, ctx.Err()
+	}))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v; want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package vault implements HashiCorp Vault backed private keys,
+// either loaded from a KV secret or kept in the transit secrets engine.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/beatlabs/github-auth/jws"
+	"github.com/beatlabs/github-auth/key"
+)
+
+// Client sends requests to the Vault HTTP API.
+// See: https://developer.hashicorp.com/vault/api-docs
+type Client struct {
+	// Address is the Vault address, e.g. https://vault.example.com:8200.
+	Address string
+
+	// Token is the Vault token of the requests.
+	Token string
+
+	// HTTPClient optionally specifies the HTTP client of the requests.
+	// If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+// maxBodySize is the maximum size of the response bodies read.
+const maxBodySize = 1 << 20
+
+// do sends a request to the Vault API path and decodes the JSON response into v.
+func (c *Client) do(ctx context.Context, method, path string, in, v interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.Address, "/")+"/v1/"+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", c.Token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		//nolint:errcheck
+		json.Unmarshal(b, &e) // the error body is optional
+		return fmt.Errorf("vault: %s %s: %d %s", method, path, resp.StatusCode, strings.Join(e.Errors, ", "))
+	}
+	return json.Unmarshal(b, v)
+}
+
+// DefaultField is the default field of the KV secrets holding the private key.
+const DefaultField = "private_key"
+
+// KV returns a key source reading the PEM private key from the field of a KV version 2 secret,
+// e.g. KV("secret", "github/app", "") for the private_key field of the secret/github/app secret.
+// If field is empty, DefaultField is used.
+// See: https://developer.hashicorp.com/vault/api-docs/secret/kv/kv-v2#read-secret-version
+func (c *Client) KV(mount, path, field string) key.Source {
+	if field == "" {
+		field = DefaultField
+	}
+	return key.SourceFunc(func(ctx context.Context) ([]byte, error) {
+		var res struct {
+			Data struct {
+				Data map[string]interface{} `json:"data"`
+			} `json:"data"`
+		}
+		if err := c.do(ctx, http.MethodGet, mount+"/data/"+path, nil, &res); err != nil {
+			return nil, err
+		}
+		s, ok := res.Data.Data[field].(string)
+		if !ok || s == "" {
+			return nil, fmt.Errorf("vault: field %q of secret %s/%s is missing", field, mount, path)
+		}
+		return []byte(s), nil
+	})
+}
+
+// DefaultSignTimeout is the default timeout of each transit signing request.
+const DefaultSignTimeout = 10 * time.Second
+
+// SignerOption configures a transit signer.
+type SignerOption func(*signerOptions)
+
+type signerOptions struct {
+	timeout time.Duration
+}
+
+// WithSignTimeout sets the timeout of each signing request, instead of DefaultSignTimeout.
+func WithSignTimeout(d time.Duration) SignerOption {
+	return func(o *signerOptions) {
+		o.timeout = d
+	}
+}
+
+// TransitSigner returns a signer of RS256 JWTs with an RSA key of the transit secrets engine,
+// so that the private key never leaves Vault, e.g. TransitSigner("transit", "github-app").
+// As jws.Signer has no context, each signing request is sent with a new context
+// limited to DefaultSignTimeout, see WithSignTimeout.
+// See: https://developer.hashicorp.com/vault/api-docs/secret/transit#sign-data
+func (c *Client) TransitSigner(mount, name string, opts ...SignerOption) jws.Signer {
+	o := signerOptions{timeout: DefaultSignTimeout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return func(data []byte) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+		defer cancel()
+		in := map[string]string{
+			"input":               base64.StdEncoding.EncodeToString(data),
+			"signature_algorithm": "pkcs1v15",
+		}
+		var res struct {
+			Data struct {
+				Signature string `json:"signature"`
+			} `json:"data"`
+		}
+		if err := c.do(ctx, http.MethodPost, mount+"/sign/"+name+"/sha2-256", in, &res); err != nil {
+			return nil, err
+		}
+		return decodeSignature(res.Data.Signature)
+	}
+}
+
+// decodeSignature decodes a transit signature, prefixed with vault:v<key version>:.
+func decodeSignature(s string) ([]byte, error) {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" {
+		return nil, errors.New("vault: invalid transit signature")
+	}
+	return base64.StdEncoding.DecodeString(parts[2])
+}
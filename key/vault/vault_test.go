@@ -0,0 +1,171 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vault
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/beatlabs/github-auth/jws"
+	"github.com/beatlabs/github-auth/key"
+)
+
+func TestClient_KV(t *testing.T) {
+	pk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(pk)})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Vault-Token"); got != "s.token" {
+			t.Errorf("token = %q; want %q", got, "s.token")
+		}
+		if r.URL.Path != "/v1/secret/data/github/app" {
+			w.WriteHeader(http.StatusNotFound)
+			//nolint:errcheck
+			w.Write([]byte(`{"errors": []}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"data":     map[string]string{"private_key": string(pemKey)},
+				"metadata": map[string]interface{}{"version": 1},
+			},
+		})
+	}))
+	defer ts.Close()
+
+	c := &Client{Address: ts.URL + "/", Token: "s.token"}
+
+	tests := map[string]struct {
+		path, field string
+		wantErr     bool
+	}{
+		"default field": {path: "github/app"},
+		"field":         {path: "github/app", field: "private_key"},
+		"missing field": {path: "github/app", field: "key", wantErr: true},
+		"missing path":  {path: "github/other", wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := key.FromSource(context.Background(), c.KV("secret", tt.path, tt.field))
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(pk) {
+				t.Error("the loaded key does not match")
+			}
+		})
+	}
+}
+
+func TestClient_TransitSigner(t *testing.T) {
+	pk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/transit/sign/github-app/sha2-256" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		var in struct {
+			Input     string `json:"input"`
+			Algorithm string `json:"signature_algorithm"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			t.Error(err)
+		}
+		if in.Algorithm != "pkcs1v15" {
+			t.Errorf("signature algorithm = %q; want pkcs1v15", in.Algorithm)
+		}
+		data, err := base64.StdEncoding.DecodeString(in.Input)
+		if err != nil {
+			t.Error(err)
+		}
+		h := sha256.Sum256(data)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, pk, crypto.SHA256, h[:])
+		if err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]string{"signature": "vault:v1:" + base64.StdEncoding.EncodeToString(sig)},
+		})
+	}))
+	defer ts.Close()
+
+	c := &Client{Address: ts.URL, Token: "s.token"}
+	signer := c.TransitSigner("transit", "github-app")
+
+	token, err := jws.EncodeWithSigner(&jws.Header{Algorithm: "RS256", Typ: "JWT"}, &jws.ClaimSet{Iss: "1"}, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := jws.Verify(token, &pk.PublicKey); err != nil {
+		t.Errorf("invalid signature: %v", err)
+	}
+}
+
+func TestClient_TransitSigner_Timeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer ts.Close()
+
+	c := &Client{Address: ts.URL, Token: "s.token"}
+	signer := c.TransitSigner("transit", "github-app", WithSignTimeout(50*time.Millisecond))
+	if _, err := signer([]byte("data")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v; want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestDecodeSignature(t *testing.T) {
+	tests := map[string]struct {
+		sig     string
+		want    string
+		wantErr bool
+	}{
+		"valid":          {sig: "vault:v2:c2ln", want: "sig"},
+		"missing prefix": {sig: "c2ln", wantErr: true},
+		"invalid base64": {sig: "vault:v1:!", wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := decodeSignature(tt.sig)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v; want error %t", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("signature = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package prommetrics implements jwt.Metrics using Prometheus.
+//
+// It is a separate module so that the Prometheus client dependency
+// is only required when the package is used.
+package prommetrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/beatlabs/github-auth/jwt"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "github_auth"
+
+var (
+	_ jwt.Metrics        = (*Metrics)(nil)
+	_ jwt.RequestMetrics = (*Metrics)(nil)
+)
+
+// Metrics is a jwt.Metrics exposing Prometheus metrics:
+//   - github_auth_token_operations_total: counter of the token operations by installation, result and status code.
+//   - github_auth_token_fetch_duration_seconds: histogram of the token fetch latency by installation and status code.
+//   - github_auth_requests_total: counter of the API requests by auth level (app or installation), ID and status code.
+//   - github_auth_request_duration_seconds: histogram of the API request latency by auth level and ID.
+type Metrics struct {
+	operations     *prometheus.CounterVec
+	latency        *prometheus.HistogramVec
+	requests       *prometheus.CounterVec
+	requestLatency *prometheus.HistogramVec
+}
+
+// New returns a new Metrics registered with the provided registerer.
+func New(reg prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "token_operations_total",
+			Help:      "Number of GitHub installation token operations.",
+		}, []string{"installation", "result", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "token_fetch_duration_seconds",
+			Help:      "Latency of GitHub installation token fetches.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"installation", "status"}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Number of GitHub API requests by authentication level.",
+		}, []string{"auth_level", "id", "status"}),
+		requestLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of GitHub API requests by authentication level.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"auth_level", "id"}),
+	}
+	for _, c := range []prometheus.Collector{m.operations, m.latency, m.requests, m.requestLatency} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// ObserveToken records a token operation.
+// Cache hits are counted but not included in the latency histogram.
+func (m *Metrics) ObserveToken(installationID, result string, statusCode int, duration time.Duration) {
+	status := strconv.Itoa(statusCode)
+	m.operations.WithLabelValues(installationID, result, status).Inc()
+	if result != jwt.ResultCacheHit {
+		m.latency.WithLabelValues(installationID, status).Observe(duration.Seconds())
+	}
+}
+
+// ObserveRequest records an API request of the App or of an installation.
+func (m *Metrics) ObserveRequest(authLevel, id string, statusCode int, duration time.Duration) {
+	m.requests.WithLabelValues(authLevel, id, strconv.Itoa(statusCode)).Inc()
+	m.requestLatency.WithLabelValues(authLevel, id).Observe(duration.Seconds())
+}
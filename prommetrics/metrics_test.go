@@ -0,0 +1,110 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package prommetrics
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/beatlabs/github-auth/app"
+	"github.com/beatlabs/github-auth/app/inst"
+	"github.com/beatlabs/github-auth/jwt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		//nolint:errcheck
+		w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+	}))
+	defer ts.Close()
+
+	reg := prometheus.NewRegistry()
+	m, err := New(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	install, err := inst.NewEnterpriseConfig(ts.URL, "1", "2", key, jwt.WithMetrics(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := install.TokenSource(context.Background()).Token(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := testutil.ToFloat64(m.operations.WithLabelValues("2", jwt.ResultSuccess, "201")); got != 1 {
+		t.Errorf("successes = %v; want 1", got)
+	}
+	if got := testutil.ToFloat64(m.operations.WithLabelValues("2", jwt.ResultCacheHit, "0")); got != 2 {
+		t.Errorf("cache hits = %v; want 2", got)
+	}
+	if got := testutil.CollectAndCount(m.latency); got != 1 {
+		t.Errorf("latency series = %d; want 1", got)
+	}
+}
+
+func TestMetrics_ObserveRequest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			//nolint:errcheck
+			w.Write([]byte(`{"token": "v1.1f699f1069f60xxx", "expires_at": "2050-01-01T11:12:13Z"}`))
+			return
+		}
+		//nolint:errcheck
+		w.Write([]byte(`{"id": 1, "slug": "octoapp"}`))
+	}))
+	defer ts.Close()
+
+	reg := prometheus.NewRegistry()
+	m, err := New(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := app.NewEnterpriseConfig(ts.URL, "1", key, jwt.WithMetrics(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.VerifyApp(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	install, err := a.InstallationConfig("2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := install.Client(context.Background()).Get(ts.URL + "/api/v3/installation/repositories")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got := testutil.ToFloat64(m.requests.WithLabelValues(jwt.AuthLevelApp, "1", "200")); got != 1 {
+		t.Errorf("app requests = %v; want 1", got)
+	}
+	if got := testutil.ToFloat64(m.requests.WithLabelValues(jwt.AuthLevelInstallation, "2", "200")); got != 1 {
+		t.Errorf("installation requests = %v; want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.requestLatency); got != 2 {
+		t.Errorf("latency series = %d; want 2", got)
+	}
+}
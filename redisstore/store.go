@@ -0,0 +1,112 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package redisstore implements a Redis backed jwt.TokenStore
+// to share installation tokens across processes.
+//
+// It is a separate module so that the Redis client dependency
+// is only required when the package is used.
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/beatlabs/github-auth/jwt"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/oauth2"
+)
+
+// DefaultPrefix is the default prefix of the Redis keys.
+const DefaultPrefix = "github-auth:token:"
+
+// SafetyMargin is subtracted from the token expiry to compute the Redis key TTL,
+// so that tokens about to expire are not shared.
+const SafetyMargin = time.Minute
+
+var _ jwt.TokenStore = (*Store)(nil)
+
+// Store is a Redis backed jwt.TokenStore.
+//
+// Tokens are stored as JSON with a TTL matching their expiry.
+// They are not encrypted, so the Redis instance must be secured accordingly.
+type Store struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// New returns a new Store using the provided Redis client and key prefix.
+// DefaultPrefix is used if the prefix is empty.
+func New(client redis.Cmdable, prefix string) *Store {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	return &Store{client: client, prefix: prefix}
+}
+
+// token is the stored representation of a token.
+type token struct {
+	AccessToken string            `json:"access_token"`
+	TokenType   string            `json:"token_type,omitempty"`
+	Expiry      time.Time         `json:"expiry"`
+	Permissions map[string]string `json:"permissions,omitempty"`
+
+	RepositorySelection string `json:"repository_selection,omitempty"`
+}
+
+// Get returns the token stored for the key, or nil if there is none.
+func (s *Store) Get(ctx context.Context, key string) (*oauth2.Token, error) {
+	b, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var t token
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, err
+	}
+	tok := &oauth2.Token{AccessToken: t.AccessToken, TokenType: t.TokenType, Expiry: t.Expiry}
+	extra := make(map[string]interface{})
+	if t.Permissions != nil {
+		perms := make(map[string]interface{}, len(t.Permissions))
+		for k, v := range t.Permissions {
+			perms[k] = v
+		}
+		extra["permissions"] = perms
+	}
+	if t.RepositorySelection != "" {
+		extra["repository_selection"] = t.RepositorySelection
+	}
+	if len(extra) > 0 {
+		tok = tok.WithExtra(extra)
+	}
+	return tok, nil
+}
+
+// Set stores the token for the key until shortly before it expires.
+// Tokens without an expiry or about to expire are not stored.
+func (s *Store) Set(ctx context.Context, key string, tok *oauth2.Token) error {
+	ttl := time.Until(tok.Expiry) - SafetyMargin
+	if tok.Expiry.IsZero() || ttl <= 0 {
+		return nil
+	}
+	t := token{AccessToken: tok.AccessToken, TokenType: tok.TokenType, Expiry: tok.Expiry}
+	// the extras are optional
+	t.Permissions, _ = jwt.ParsedPermissions(tok)
+	t.RepositorySelection, _ = jwt.ParsedRepositorySelection(tok)
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, s.prefix+key, b, ttl).Err()
+}
+
+// Delete removes the token stored for the key.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.prefix+key).Err()
+}
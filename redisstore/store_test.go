@@ -0,0 +1,104 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/beatlabs/github-auth/jwt"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/oauth2"
+)
+
+func newStore(t *testing.T) (*Store, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return New(client, ""), mr
+}
+
+func TestStore(t *testing.T) {
+	s, mr := newStore(t)
+	ctx := context.Background()
+
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	tok := (&oauth2.Token{AccessToken: "v1.1f699f1069f60xxx", TokenType: "token", Expiry: expiry}).
+		WithExtra(map[string]interface{}{
+			"permissions":          map[string]interface{}{"contents": "read"},
+			"repository_selection": "all",
+		})
+	if err := s.Set(ctx, "2", tok); err != nil {
+		t.Fatal(err)
+	}
+
+	ttl := mr.TTL(DefaultPrefix + "2")
+	if ttl <= 0 || ttl > time.Hour-SafetyMargin {
+		t.Errorf("ttl = %v; want at most %v", ttl, time.Hour-SafetyMargin)
+	}
+	raw, err := mr.Get(DefaultPrefix + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"access_token":"v1.1f699f1069f60xxx","token_type":"token","expiry":"` + expiry.Format(time.RFC3339Nano) + `","permissions":{"contents":"read"},"repository_selection":"all"}`
+	if raw != want {
+		t.Errorf("stored = %s; want %s", raw, want)
+	}
+
+	got, err := s.Get(ctx, "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.AccessToken != tok.AccessToken || got.TokenType != tok.TokenType || !got.Expiry.Equal(expiry) {
+		t.Errorf("got %+v; want %+v", got, tok)
+	}
+	perms, err := jwt.ParsedPermissions(got)
+	if err != nil || perms["contents"] != "read" {
+		t.Errorf("permissions = %v, %v; want contents:read", perms, err)
+	}
+	if rs, err := jwt.ParsedRepositorySelection(got); err != nil || rs != "all" {
+		t.Errorf("repository selection = %q, %v; want all", rs, err)
+	}
+
+	if err := s.Delete(ctx, "2"); err != nil {
+		t.Fatal(err)
+	}
+	got, err = s.Get(ctx, "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Errorf("got %+v; want none", got)
+	}
+}
+
+func TestStore_Expired(t *testing.T) {
+	s, mr := newStore(t)
+	ctx := context.Background()
+
+	tok := &oauth2.Token{AccessToken: "v1.1f699f1069f60xxx", Expiry: time.Now().Add(30 * time.Second)}
+	if err := s.Set(ctx, "2", tok); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists(DefaultPrefix + "2") {
+		t.Error("stored a token about to expire")
+	}
+
+	tok.Expiry = time.Now().Add(2 * SafetyMargin)
+	if err := s.Set(ctx, "2", tok); err != nil {
+		t.Fatal(err)
+	}
+	mr.FastForward(2 * SafetyMargin)
+	got, err := s.Get(ctx, "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Errorf("got %+v; want none after the TTL", got)
+	}
+}
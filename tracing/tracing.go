@@ -0,0 +1,78 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tracing implements a jwt.Tracer using OpenTelemetry.
+//
+// It is a separate module so that the OpenTelemetry dependency
+// is only required when the package is used.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/beatlabs/github-auth/jwt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is the name of the OpenTelemetry tracer.
+const InstrumentationName = "github.com/beatlabs/github-auth"
+
+var _ jwt.Tracer = Tracer{}
+
+// Tracer is a jwt.Tracer starting OpenTelemetry spans.
+//
+// The spans are started using the tracer provider of the span in the context,
+// or the global tracer provider if the context has no span.
+type Tracer struct{}
+
+// New returns a new Tracer.
+func New() Tracer {
+	return Tracer{}
+}
+
+// Start starts a span as a child of the span in the context, if any.
+func (Tracer) Start(ctx context.Context, name string) (context.Context, jwt.Span) {
+	tp := otel.GetTracerProvider()
+	if s := trace.SpanFromContext(ctx); s.SpanContext().IsValid() {
+		tp = s.TracerProvider()
+	}
+	ctx, s := tp.Tracer(InstrumentationName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, span{s}
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s span) SetAttributes(keysAndValues ...interface{}) {
+	attrs := make([]attribute.KeyValue, 0, len(keysAndValues)/2)
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+		switch v := keysAndValues[i+1].(type) {
+		case string:
+			attrs = append(attrs, attribute.String(key, v))
+		case int:
+			attrs = append(attrs, attribute.Int(key, v))
+		case int64:
+			attrs = append(attrs, attribute.Int64(key, v))
+		case bool:
+			attrs = append(attrs, attribute.Bool(key, v))
+		default:
+			attrs = append(attrs, attribute.String(key, fmt.Sprint(v)))
+		}
+	}
+	s.span.SetAttributes(attrs...)
+}
+
+func (s span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
@@ -0,0 +1,78 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/beatlabs/github-auth/app/inst"
+	"github.com/beatlabs/github-auth/jwt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	install, err := inst.NewEnterpriseConfig(ts.URL, "1", "2", key, jwt.WithTracer(New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = install.TokenSource(ctx).Token()
+	if err == nil {
+		t.Fatal("got no error, expected one")
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d; want 2", len(spans))
+	}
+	s := spans[0]
+	if got, want := s.Name(), jwt.TokenSpanName; got != want {
+		t.Errorf("name = %q; want %q", got, want)
+	}
+	if got, want := s.Parent().SpanID(), parent.SpanContext().SpanID(); got != want {
+		t.Errorf("parent = %v; want %v", got, want)
+	}
+	if got, want := s.Status().Code, codes.Error; got != want {
+		t.Errorf("status = %v; want %v", got, want)
+	}
+	want := map[attribute.Key]attribute.Value{
+		"github.app_id":          attribute.StringValue("1"),
+		"github.installation_id": attribute.StringValue("2"),
+		"http.status_code":       attribute.IntValue(http.StatusUnauthorized),
+		"github.retry_count":     attribute.IntValue(0),
+	}
+	for _, kv := range s.Attributes() {
+		if v, ok := want[kv.Key]; ok {
+			if kv.Value != v {
+				t.Errorf("%s = %v; want %v", kv.Key, kv.Value.Emit(), v.Emit())
+			}
+			delete(want, kv.Key)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("missing attributes %v", want)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+)
+
+// maxPayloadSize is the maximum size of a webhook payload delivered by GitHub.
+const maxPayloadSize = 25 << 20
+
+type payloadKey struct{}
+
+// Middleware returns a middleware validating the X-Hub-Signature-256 signature
+// of the webhook deliveries using the webhook secret.
+// Requests with a missing or invalid signature are rejected with 401 Unauthorized.
+//
+// The validated payload is available to the wrapped handler
+// both as the request body and using Payload.
+func Middleware(secret []byte) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxPayloadSize+1))
+			if err != nil {
+				http.Error(w, "failed to read payload", http.StatusBadRequest)
+				return
+			}
+			if len(body) > maxPayloadSize {
+				http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			if err := ValidateSignature(secret, body, r.Header.Get(SignatureHeader)); err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), payloadKey{}, body))
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Payload returns the payload validated by Middleware.
+// It reports false if the request was not handled by Middleware.
+func Payload(r *http.Request) ([]byte, bool) {
+	body, ok := r.Context().Value(payloadKey{}).([]byte)
+	return body, ok
+}
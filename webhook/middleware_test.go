@@ -0,0 +1,58 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webhook
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := map[string]struct {
+		body       []byte
+		signature  string
+		wantStatus int
+	}{
+		"valid":   {body: testPayload, signature: testSHA256, wantStatus: http.StatusOK},
+		"missing": {body: testPayload, wantStatus: http.StatusUnauthorized},
+		"invalid": {body: []byte("Hello, World?"), signature: testSHA256, wantStatus: http.StatusUnauthorized},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			called := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(body, tt.body) {
+					t.Errorf("body = %q; want %q", body, tt.body)
+				}
+				payload, ok := Payload(r)
+				if !ok || !bytes.Equal(payload, tt.body) {
+					t.Errorf("payload = %q; want %q", payload, tt.body)
+				}
+			})
+
+			r := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(tt.body))
+			if tt.signature != "" {
+				r.Header.Set(SignatureHeader, tt.signature)
+			}
+			w := httptest.NewRecorder()
+			Middleware(testSecret)(next).ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d; want %d", w.Code, tt.wantStatus)
+			}
+			if called != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("handler called = %t; want %t", called, !called)
+			}
+		})
+	}
+}
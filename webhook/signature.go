@@ -0,0 +1,69 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package webhook implements GitHub App webhook signature validation.
+//
+// See: https://docs.github.com/en/webhooks/using-webhooks/validating-webhook-deliveries
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // required by the legacy X-Hub-Signature header
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+const (
+	// SignatureHeader is the header holding the HMAC SHA-256 signature of the payload.
+	SignatureHeader = "X-Hub-Signature-256"
+
+	// LegacySignatureHeader is the header holding the legacy HMAC SHA-1 signature of the payload.
+	LegacySignatureHeader = "X-Hub-Signature"
+)
+
+var (
+	// ErrMissingSignature is returned when the signature header is empty.
+	ErrMissingSignature = errors.New("webhook: missing signature")
+
+	// ErrInvalidSignature is returned when the signature does not match the payload.
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+)
+
+// ValidateSignature validates the signature header value (e.g. "sha256=<hex digest>")
+// of the payload body using the webhook secret.
+// Both sha256 and legacy sha1 signatures are supported.
+// The signatures are compared in constant time.
+func ValidateSignature(secret, body []byte, header string) error {
+	if header == "" {
+		return ErrMissingSignature
+	}
+	alg, sig, ok := strings.Cut(header, "=")
+	if !ok {
+		return fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	var h func() hash.Hash
+	switch alg {
+	case "sha256":
+		h = sha256.New
+	case "sha1":
+		h = sha1.New
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidSignature, alg)
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("%w: malformed digest", ErrInvalidSignature)
+	}
+	mac := hmac.New(h, secret)
+	//nolint:errcheck
+	mac.Write(body) // never returns an error
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
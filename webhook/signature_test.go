@@ -0,0 +1,44 @@
+// Copyright 2021 Beat Research B.V. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webhook
+
+import (
+	"errors"
+	"testing"
+)
+
+// Example from https://docs.github.com/en/webhooks/using-webhooks/validating-webhook-deliveries#testing-the-webhook-payload-validation
+var (
+	testSecret  = []byte("It's a Secret to Everybody")
+	testPayload = []byte("Hello, World!")
+	testSHA256  = "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+	testSHA1    = "sha1=01dc10d0c83e72ed246219cdd91669667fe2ca59"
+)
+
+func TestValidateSignature(t *testing.T) {
+	tests := map[string]struct {
+		secret  []byte
+		body    []byte
+		header  string
+		wantErr error
+	}{
+		"sha256":           {secret: testSecret, body: testPayload, header: testSHA256},
+		"sha1":             {secret: testSecret, body: testPayload, header: testSHA1},
+		"tampered payload": {secret: testSecret, body: []byte("Hello, World?"), header: testSHA256, wantErr: ErrInvalidSignature},
+		"wrong secret":     {secret: []byte("secret"), body: testPayload, header: testSHA256, wantErr: ErrInvalidSignature},
+		"missing":          {secret: testSecret, body: testPayload, wantErr: ErrMissingSignature},
+		"malformed":        {secret: testSecret, body: testPayload, header: "757107ea", wantErr: ErrInvalidSignature},
+		"unsupported":      {secret: testSecret, body: testPayload, header: "md5=757107ea", wantErr: ErrInvalidSignature},
+		"malformed digest": {secret: testSecret, body: testPayload, header: "sha256=xyz", wantErr: ErrInvalidSignature},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateSignature(tt.secret, tt.body, tt.header)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v; want %v", err, tt.wantErr)
+			}
+		})
+	}
+}